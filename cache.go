@@ -0,0 +1,109 @@
+package task
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/leiyangyou/task/v2/internal/cache"
+	"github.com/leiyangyou/task/v2/internal/status"
+	"github.com/leiyangyou/task/v2/internal/taskfile"
+)
+
+// cacheKey returns the key under which the generated files of the task are
+// cached, or an empty string if the cache should not be used for it. Tasks
+// without sources are not cached, since their fingerprint wouldn't change
+// with their inputs
+func (e *Executor) cacheKey(ctx context.Context, t *taskfile.Task) string {
+	if !t.Cache || e.isForced(ctx) || e.Dry || len(t.Generates) == 0 {
+		return ""
+	}
+	if len(t.Sources) == 0 {
+		e.Logger.VerboseErrf(`task: Task "%s" has no sources, not using the cache`, t.Task)
+		return ""
+	}
+	if e.cacheStore() == nil {
+		return ""
+	}
+
+	key, err := cache.Fingerprint(t)
+	if err != nil {
+		e.Logger.VerboseErrf("task: unable to fingerprint task %q: %v", t.Task, err)
+		return ""
+	}
+	return key
+}
+
+// defaultCache is the cache used when Cache is not given. It's shared by
+// the copies of the Executor made on watch mode
+type defaultCache struct {
+	once  sync.Once
+	store cache.Store
+}
+
+// cacheStore returns Cache, or the default cache, creating it on its first
+// use. It's nil if the default cache directory can't be found
+func (e *Executor) cacheStore() cache.Store {
+	if e.Cache != nil {
+		return e.Cache
+	}
+	d := e.defaultCache
+	d.once.Do(func() {
+		dir, err := cache.DefaultDir()
+		if err != nil {
+			e.Logger.Errf("task: unable to use the cache: %v", err)
+			return
+		}
+		d.store = &cache.Local{Dir: dir}
+
+		if e.RemoteCache != "" {
+			d.store = &cache.Layered{
+				Local:    d.store,
+				Remote:   &cache.HTTP{URL: e.RemoteCache, Timeout: e.RemoteCacheTimeout},
+				ReadOnly: e.CacheReadOnly,
+			}
+		}
+	})
+	return d.store
+}
+
+// restoreFromCache restores the generated files of the task from the cache,
+// returning false on a cache miss
+func (e *Executor) restoreFromCache(t *taskfile.Task, key string) bool {
+	r, err := e.cacheStore().Get(key)
+	if err != nil {
+		if err != cache.ErrNotFound {
+			e.Logger.Errf("task: unable to read cache for task %q: %v", t.Task, err)
+		}
+		return false
+	}
+	defer r.Close()
+
+	if err = cache.Unpack(r, t.Dir); err != nil {
+		e.Logger.Errf("task: unable to restore cache for task %q: %v", t.Task, err)
+		return false
+	}
+
+	if !e.Silent {
+		e.Logger.Errf(`task: Task "%s" restored from cache`, t.Task)
+	}
+	return true
+}
+
+func (e *Executor) saveToCache(t *taskfile.Task, key string) {
+	files, err := status.Glob(t.Dir, t.Generates)
+	if err != nil {
+		e.Logger.VerboseErrf("task: unable to cache task %q: %v", t.Task, err)
+		return
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(cache.Pack(w, t.Dir, files))
+	}()
+
+	if err = e.cacheStore().Put(key, r); err != nil {
+		e.Logger.VerboseErrf("task: unable to cache task %q: %v", t.Task, err)
+	}
+	r.Close()
+}
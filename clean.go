@@ -0,0 +1,87 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leiyangyou/task/v2/internal/status"
+	"github.com/leiyangyou/task/v2/internal/taskfile"
+)
+
+// Clean removes the files generated by the given tasks and their status
+// state, so they run again on the next call. Files outside of the Taskfile
+// directory are only removed if Force is set.
+func (e *Executor) Clean(calls ...taskfile.Call) error {
+	root, err := filepath.Abs(e.taskfileDir)
+	if err != nil {
+		return err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, call := range calls {
+		t, err := e.CompiledTask(call)
+		if err != nil {
+			return err
+		}
+
+		files, err := status.Glob(t.Dir, t.Generates)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if !e.Force && !isInsideDir(root, f) {
+				return fmt.Errorf(`task: Refusing to remove "%s" of task "%s" because it's outside of the Taskfile directory, use --force to remove it anyway`, f, t.Task)
+			}
+		}
+		paths = append(paths, files...)
+
+		for _, f := range status.StatePaths(t.Dir, t.Task) {
+			if _, err := os.Stat(f); err != nil {
+				continue
+			}
+			if !e.Force && !isInsideDir(root, f) {
+				return fmt.Errorf(`task: Refusing to remove "%s" of task "%s" because it's outside of the Taskfile directory, use --force to remove it anyway`, f, t.Task)
+			}
+			paths = append(paths, f)
+		}
+	}
+
+	for _, p := range paths {
+		if e.Dry {
+			e.Logger.Outf("task: Would remove %s", p)
+			continue
+		}
+		if !e.Silent {
+			e.Logger.Errf("task: Removing %s", p)
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isInsideDir reports whether path is inside dir, which must have its
+// symlinks resolved. The symlinks of the directory of path are resolved, so
+// it can't escape dir through them, but path itself can be a symlink, as
+// removing it doesn't remove its target
+func isInsideDir(dir, path string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(dir, filepath.Join(parent, filepath.Base(path)))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
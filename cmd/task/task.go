@@ -2,13 +2,18 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	"github.com/leiyangyou/task/v2"
 	"github.com/leiyangyou/task/v2/internal/args"
+	"github.com/leiyangyou/task/v2/internal/cache"
 
 	"github.com/spf13/pflag"
 )
@@ -17,7 +22,7 @@ var (
 	version = "master"
 )
 
-const usage = `Usage: task [-ilfwvsd] [--init] [--list] [--force] [--watch] [--verbose] [--silent] [--dir] [--dry] [--summary] [task...]
+const usage = `Usage: task [-ilfwvsdtg] [--init] [--list] [--force] [--watch] [--verbose] [--silent] [--dir] [--taskfile] [--dry] [--summary] [--global] [task...]
 
 Runs the specified task(s). Falls back to the "default" task if no task name
 was specified, or lists all tasks if an unknown task name was specified.
@@ -51,28 +56,68 @@ func main() {
 		init        bool
 		list        bool
 		status      bool
+		jsonFlag    bool
+		recursive   bool
+		clean       bool
 		force       bool
 		watch       bool
+		watchPoll   bool
+		interval    time.Duration
+		onSuccess   string
+		onFailure   string
 		verbose     bool
 		silent      bool
 		dry         bool
 		summary     bool
 		dir         string
+		entrypoint  string
+		profile     string
+		envFiles    []string
 		output      string
+
+		global          bool
+		userTasks       bool
+		offline         bool
+		allowDuplicates bool
+
+		remoteCache        string
+		remoteCacheTimeout time.Duration
+		cacheReadOnly      bool
+		cacheServer        string
 	)
 
+	userTasksDefault, _ := strconv.ParseBool(os.Getenv("TASK_USER_TASKS"))
+
 	pflag.BoolVar(&versionFlag, "version", false, "show Task version")
 	pflag.BoolVarP(&init, "init", "i", false, "creates a new Taskfile.yml in the current folder")
 	pflag.BoolVarP(&list, "list", "l", false, "lists tasks with description of current Taskfile")
 	pflag.BoolVar(&status, "status", false, "exits with non-zero exit code if any of the given tasks is not up-to-date")
+	pflag.BoolVar(&jsonFlag, "json", false, "with --status, checks all the given tasks and prints a JSON report")
+	pflag.BoolVar(&recursive, "recursive", false, "with --status, also checks the dependencies of the given tasks")
+	pflag.BoolVar(&clean, "clean", false, "removes the files generated by the given tasks and their status state")
 	pflag.BoolVarP(&force, "force", "f", false, "forces execution even when the task is up-to-date")
 	pflag.BoolVarP(&watch, "watch", "w", false, "enables watch of the given task")
+	pflag.BoolVar(&watchPoll, "watch-poll", false, "with --watch, polls the file system for changes instead of using native notifications")
+	pflag.DurationVar(&interval, "interval", 0, "with --watch, time to wait for changes to settle before rerunning, e.g. 1s (default 500ms)")
+	pflag.StringVar(&onSuccess, "on-success", "", "with --watch, command to run after each successful run")
+	pflag.StringVar(&onFailure, "on-failure", "", "with --watch, command to run after each failed run")
+	pflag.BoolVarP(&global, "global", "g", false, "runs the tasks of the user Taskfile, on ~/.config/task, instead of the ones of the current folder")
+	pflag.BoolVar(&userTasks, "user-tasks", userTasksDefault, "includes the tasks of the user Taskfile on the user namespace (default $TASK_USER_TASKS)")
+	pflag.BoolVar(&offline, "offline", false, "uses only the local cache for remote includes")
+	pflag.BoolVar(&allowDuplicates, "allow-duplicates", false, "warns instead of failing when included Taskfiles define the same task")
 	pflag.BoolVarP(&verbose, "verbose", "v", false, "enables verbose mode")
 	pflag.BoolVarP(&silent, "silent", "s", false, "disables echoing")
 	pflag.BoolVar(&dry, "dry", false, "compiles and prints tasks in the order that they would be run, without executing them")
 	pflag.BoolVar(&summary, "summary", false, "show summary about a task")
 	pflag.StringVarP(&dir, "dir", "d", "", "sets directory of execution")
+	pflag.StringVarP(&entrypoint, "taskfile", "t", "", "path of the Taskfile to run, instead of looking for it on the current folder and its parents")
+	pflag.StringVar(&profile, "profile", os.Getenv("TASK_PROFILE"), "selects a profile of the Taskfile, overriding its vars and env (default $TASK_PROFILE)")
+	pflag.StringArrayVar(&envFiles, "env-file", nil, "reads env vars and vars from a dotenv file, overriding the dotenv files of the Taskfile but not its env and vars. Can be given more than once")
 	pflag.StringVarP(&output, "output", "o", "", "sets output style: [interleaved|group|prefixed]")
+	pflag.StringVar(&remoteCache, "remote-cache", os.Getenv("TASK_REMOTE_CACHE"), "URL of a remote cache for generated files")
+	pflag.DurationVar(&remoteCacheTimeout, "remote-cache-timeout", cache.DefaultHTTPTimeout, "timeout of the requests to the remote cache, after which downloads are cache misses")
+	pflag.BoolVar(&cacheReadOnly, "cache-read-only", false, "disables uploads to the remote cache, or rejects them with --cache-server")
+	pflag.StringVar(&cacheServer, "cache-server", "", "serves the local cache over HTTP on the given address, e.g. :9000")
 	pflag.Parse()
 
 	if versionFlag {
@@ -91,6 +136,18 @@ func main() {
 		return
 	}
 
+	if cacheServer != "" {
+		dir, err := cache.DefaultDir()
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("task: Serving cache %s on %s", dir, cacheServer)
+		log.Fatal(http.ListenAndServe(cacheServer, &cache.Server{
+			Store:    &cache.Local{Dir: dir},
+			ReadOnly: cacheReadOnly,
+		}))
+	}
+
 	e := task.Executor{
 		Force:   force,
 		Watch:   watch,
@@ -100,11 +157,29 @@ func main() {
 		Dry:     dry,
 		Summary: summary,
 
+		Entrypoint: entrypoint,
+		Profile:    profile,
+		EnvFiles:   envFiles,
+
 		Stdin:  os.Stdin,
 		Stdout: os.Stdout,
 		Stderr: os.Stderr,
 
 		OutputStyle: output,
+
+		Interval:  interval,
+		WatchPoll: watchPoll,
+		OnSuccess: onSuccess,
+		OnFailure: onFailure,
+
+		Global:          global,
+		UserTasks:       userTasks,
+		Offline:         offline,
+		AllowDuplicates: allowDuplicates,
+
+		RemoteCache:        remoteCache,
+		RemoteCacheTimeout: remoteCacheTimeout,
+		CacheReadOnly:      cacheReadOnly,
 	}
 	if err := e.Setup(); err != nil {
 		log.Fatal(err)
@@ -131,6 +206,24 @@ func main() {
 		ctx = getSignalContext()
 	}
 
+	if status && (jsonFlag || recursive) {
+		report, err := e.StatusReport(ctx, recursive, calls...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if jsonFlag {
+			printStatusReport(report)
+		} else {
+			e.PrintStatusTree(report, calls...)
+		}
+		for _, s := range report {
+			if !s.UpToDate {
+				log.Fatalf(`task: Task "%s" is not up-to-date`, s.Task)
+			}
+		}
+		return
+	}
+
 	if status {
 		if err := e.Status(ctx, calls...); err != nil {
 			log.Fatal(err)
@@ -138,11 +231,28 @@ func main() {
 		return
 	}
 
+	if clean {
+		if err := e.Clean(calls...); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if err := e.Run(ctx, calls...); err != nil {
 		log.Fatal(err)
 	}
 }
 
+func printStatusReport(report []task.TaskStatus) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(struct {
+		Tasks []task.TaskStatus `json:"tasks"`
+	}{report}); err != nil {
+		log.Fatal(err)
+	}
+}
+
 func getSignalContext() context.Context {
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt, os.Kill, syscall.SIGTERM)
@@ -0,0 +1,118 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/leiyangyou/task/v2/internal/term"
+)
+
+const consoleHelp = "r: rerun, f: force rerun, c: clear, p: watched paths, q: quit"
+
+// watchConsole reads key presses from the terminal to control watch mode,
+// and prints the result of each run
+type watchConsole struct {
+	e         *Executor
+	in        io.ReadCloser
+	restore   func() error
+	stdin     io.Reader
+	interrupt func()
+	commands  []chan watchCommand
+	// done is closed once the console stopped reading
+	done chan void
+}
+
+// startConsole starts the console if stdin is a terminal, and does nothing
+// otherwise. While it runs, commands don't read from stdin
+func (e *Executor) startConsole(interrupt func(), commands []chan watchCommand) error {
+	f, ok := e.Stdin.(*os.File)
+	if !ok || !term.IsTerminal(f.Fd()) {
+		return nil
+	}
+
+	restore, err := term.CBreak(f.Fd())
+	if err != nil {
+		return err
+	}
+	in, err := term.NewReader(f)
+	if err != nil {
+		_ = restore()
+		return err
+	}
+
+	e.runConsole(in, restore, interrupt, commands)
+	return nil
+}
+
+// runConsole reads key presses from in until it's closed by stopConsole,
+// which calls restore afterwards
+func (e *Executor) runConsole(in io.ReadCloser, restore func() error, interrupt func(), commands []chan watchCommand) {
+	e.console = &watchConsole{
+		e:         e,
+		in:        in,
+		restore:   restore,
+		stdin:     e.Stdin,
+		interrupt: interrupt,
+		commands:  commands,
+		done:      make(chan void),
+	}
+	e.Stdin = nil
+
+	e.Logger.Outf("task: Press a key to control watch mode (%s)", consoleHelp)
+	go e.console.read()
+}
+
+// stopConsole stops reading key presses, and restores the terminal
+func (e *Executor) stopConsole() {
+	if e.console == nil {
+		return
+	}
+	if err := e.console.in.Close(); err != nil {
+		e.Logger.VerboseErrf("task: Unable to stop reading the terminal: %v", err)
+	}
+	<-e.console.done
+	if err := e.console.restore(); err != nil {
+		e.Logger.Errf("task: Unable to restore the terminal: %v", err)
+	}
+	e.Stdin = e.console.stdin
+	e.console = nil
+}
+
+func (c *watchConsole) read() {
+	defer close(c.done)
+
+	buf := make([]byte, 1)
+	for {
+		if _, err := c.in.Read(buf); err != nil {
+			return
+		}
+
+		switch buf[0] {
+		case 'r':
+			sendCommand(c.commands, watchRerun)
+		case 'f':
+			sendCommand(c.commands, watchForceRerun)
+		case 'c':
+			fmt.Fprint(c.e.Stdout, "\033[H\033[2J")
+		case 'p':
+			sendCommand(c.commands, watchPrintPaths)
+		case 'q':
+			c.interrupt()
+			return
+		}
+	}
+}
+
+// printStatus prints the result and duration of the last run of a task
+func (c *watchConsole) printStatus(task string, duration time.Duration, err error) {
+	now := time.Now().Format("15:04:05")
+	duration = duration.Round(time.Millisecond)
+
+	if err != nil {
+		c.e.Logger.Errf("task: [%s] %s failed after %v (%s)", now, task, duration, consoleHelp)
+		return
+	}
+	c.e.Logger.Outf("task: [%s] %s succeeded in %v (%s)", now, task, duration, consoleHelp)
+}
@@ -0,0 +1,78 @@
+package task
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/leiyangyou/task/v2/internal/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsoleKeys(t *testing.T) {
+	tests := []struct {
+		key     byte
+		command watchCommand
+		output  string
+		quit    bool
+	}{
+		{key: 'r', command: watchRerun},
+		{key: 'f', command: watchForceRerun},
+		{key: 'p', command: watchPrintPaths},
+		{key: 'c', command: -1, output: "\033[H\033[2J"},
+		{key: 'q', command: -1, quit: true},
+		{key: 'x', command: -1},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.key), func(t *testing.T) {
+			var stdout bytes.Buffer
+			e := &Executor{Stdout: &stdout, Logger: &logger.Logger{Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}}
+			commands := []chan watchCommand{make(chan watchCommand, 1)}
+			quit := make(chan void)
+			restored := false
+
+			pr, pw := io.Pipe()
+			e.runConsole(pr, func() error { restored = true; return nil }, func() { close(quit) }, commands)
+			console := e.console
+
+			_, err := pw.Write([]byte{test.key})
+			assert.NoError(t, err)
+
+			if test.quit {
+				select {
+				case <-quit:
+				case <-time.After(time.Second):
+					t.Fatal("timed out waiting for the interrupt")
+				}
+				select {
+				case <-console.done:
+				case <-time.After(time.Second):
+					t.Fatal("the console kept reading after quitting")
+				}
+			} else {
+				// a second key makes sure the first one was handled
+				_, err = pw.Write([]byte{'x'})
+				assert.NoError(t, err)
+			}
+
+			e.stopConsole()
+			assert.True(t, restored)
+			assert.Nil(t, e.console)
+
+			// the reader was closed, and the console stopped reading it
+			_, err = pw.Write([]byte{'r'})
+			assert.Equal(t, io.ErrClosedPipe, err)
+
+			select {
+			case cmd := <-commands[0]:
+				assert.Equal(t, test.command, cmd)
+			default:
+				assert.Equal(t, watchCommand(-1), test.command)
+			}
+			assert.Equal(t, test.output, stdout.String())
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package task
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/leiyangyou/task/v2/internal/compiler"
+	"github.com/leiyangyou/task/v2/internal/execext"
+	"github.com/leiyangyou/task/v2/internal/taskfile"
+	"github.com/leiyangyou/task/v2/internal/taskfile/read"
+	"github.com/leiyangyou/task/v2/internal/templater"
+)
+
+// readDotenv reads the dotenv files of the Taskfile, relative to its
+// directory, and then the ones of EnvFiles, which override them. The paths
+// of the Taskfile can use its vars. The paths of all the files are returned
+// as well, to be watched
+func (e *Executor) readDotenv(tf *taskfile.Taskfile, c compiler.Compiler, dir string) (taskfile.Vars, []string, error) {
+	paths := tf.Dotenv
+	if len(paths) > 0 {
+		vars, err := c.GetVariables(&taskfile.Task{TaskfileVars: tf.Vars}, taskfile.Call{})
+		if err != nil {
+			return nil, nil, err
+		}
+		r := templater.Templater{Vars: vars}
+		paths = r.ReplaceSlice(paths)
+		if err = r.Err(); err != nil {
+			return nil, nil, err
+		}
+	}
+	vars, files, err := read.DotenvFiles(dir, paths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// unlike the ones of the Taskfile, the files given explicitly must exist
+	for _, path := range e.EnvFiles {
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil, fmt.Errorf(`task: Env file "%s" not found`, path)
+		}
+	}
+	envFileVars, envFiles, err := read.DotenvFiles("", e.EnvFiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range envFileVars {
+		vars[k] = v
+	}
+	return vars, append(files, envFiles...), nil
+}
+
+// taskDotenv reads the dotenv files of a task, relative to its directory.
+// Their paths can use the vars of the task
+func (e *Executor) taskDotenv(t *taskfile.Task, vars taskfile.Vars) (taskfile.Vars, error) {
+	if len(t.Dotenv) == 0 {
+		return nil, nil
+	}
+
+	r := templater.Templater{Vars: vars}
+	paths := r.ReplaceSlice(t.Dotenv)
+	dir := r.Replace(t.Dir)
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	dir, err := execext.Expand(dir)
+	if err != nil {
+		return nil, err
+	}
+	if e.Dir != "" && !filepath.IsAbs(dir) {
+		dir = filepath.Join(e.Dir, dir)
+	}
+
+	dotenv, _, err := read.DotenvFiles(dir, paths)
+	return dotenv, err
+}
@@ -27,6 +27,16 @@ func (err *taskRunError) Error() string {
 	return fmt.Sprintf(`task: Failed to run task "%s": %v`, err.taskName, err.err)
 }
 
+// cyclicDepError is returned on watch mode when a task calls itself,
+// directly or through other tasks
+type cyclicDepError struct {
+	task string
+}
+
+func (err *cyclicDepError) Error() string {
+	return fmt.Sprintf(`task: cyclic dependency detected on task "%s"`, err.task)
+}
+
 // MaximumTaskCallExceededError is returned when a task is called too
 // many times. In this case you probably have a cyclic dependendy or
 // infinite loop
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Pack writes a gzipped tarball of the given files to w. Files are stored
+// relative to dir, and must be inside it. Directories are packed with all
+// the files inside them.
+func Pack(w io.Writer, dir string, files []string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	// a file can be given both directly and through its directory
+	packed := make(map[string]bool)
+	packFile := func(path string, info os.FileInfo) error {
+		rel, err := filepath.Rel(absDir, path)
+		if err != nil || !isLocal(rel) {
+			return fmt.Errorf(`task: generated file "%s" is outside of "%s"`, path, dir)
+		}
+		if packed[rel] || !info.Mode().IsRegular() {
+			return nil
+		}
+		packed[rel] = true
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		return copyFile(tw, path)
+	}
+
+	for _, f := range files {
+		absFile, err := filepath.Abs(f)
+		if err != nil {
+			return err
+		}
+		if rel, err := filepath.Rel(absDir, absFile); err != nil || !isLocal(rel) {
+			return fmt.Errorf(`task: generated file "%s" is outside of "%s"`, f, dir)
+		}
+
+		err = filepath.Walk(absFile, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			return packFile(path, info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// Unpack extracts a tarball written by Pack into dir. Restored files get
+// the current time as modification time, so timestamp checks see them as
+// freshly generated.
+func Unpack(r io.Reader, dir string) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gr.Close()
+
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := filepath.FromSlash(hdr.Name)
+		if !isLocal(name) {
+			return fmt.Errorf(`task: invalid path "%s" in cache entry`, hdr.Name)
+		}
+		path := filepath.Join(dir, name)
+
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(hdr.Mode)&os.ModePerm)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(f, tr); err != nil {
+			f.Close()
+			return err
+		}
+		if err = f.Close(); err != nil {
+			return err
+		}
+	}
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func isLocal(rel string) bool {
+	return !filepath.IsAbs(rel) && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
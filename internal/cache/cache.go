@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+var (
+	// ErrNotFound is returned by a Store when there is no entry for a key
+	ErrNotFound = errors.New("task: cache entry not found")
+)
+
+// Store stores archives of generated files by task fingerprint
+type Store interface {
+	Get(key string) (io.ReadCloser, error)
+	Put(key string, r io.Reader) error
+}
+
+// DefaultDir returns the directory of the local cache: $TASK_CACHE_DIR if
+// set, ~/.cache/task otherwise
+func DefaultDir() (string, error) {
+	if dir := os.Getenv("TASK_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".cache", "task"), nil
+}
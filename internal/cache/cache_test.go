@@ -0,0 +1,149 @@
+package cache_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/leiyangyou/task/v2/internal/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "task-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l := &cache.Local{Dir: dir}
+
+	_, err = l.Get("abcdef")
+	assert.Equal(t, cache.ErrNotFound, err)
+
+	assert.NoError(t, l.Put("abcdef", bytes.NewBufferString("content")))
+
+	r, err := l.Get("abcdef")
+	assert.NoError(t, err)
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(b))
+}
+
+func TestPackUnpack(t *testing.T) {
+	src, err := ioutil.TempDir("", "task-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(src)
+	dest, err := ioutil.TempDir("", "task-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dest)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(src, "sub"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(src, "sub", "b.txt"), []byte("b"), 0755))
+
+	var buff bytes.Buffer
+	files := []string{filepath.Join(src, "a.txt"), filepath.Join(src, "sub"), filepath.Join(src, "sub", "b.txt")}
+	assert.NoError(t, cache.Pack(&buff, src, files))
+	assert.NoError(t, cache.Unpack(&buff, dest))
+
+	b, err := ioutil.ReadFile(filepath.Join(dest, "a.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(b))
+	info, err := os.Stat(filepath.Join(dest, "sub", "b.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	// directories are packed with their contents
+	assert.NoError(t, os.RemoveAll(dest))
+	buff.Reset()
+	assert.NoError(t, cache.Pack(&buff, src, []string{filepath.Join(src, "sub")}))
+	assert.NoError(t, cache.Unpack(&buff, dest))
+	b, err = ioutil.ReadFile(filepath.Join(dest, "sub", "b.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "b", string(b))
+	_, err = os.Stat(filepath.Join(dest, "a.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	buff.Reset()
+	assert.Error(t, cache.Pack(&buff, filepath.Join(src, "sub"), []string{filepath.Join(src, "a.txt")}),
+		"files outside of the directory should not be packed")
+}
+
+func TestHTTP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "task-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	server := &cache.Server{Store: &cache.Local{Dir: dir}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	const key = "0123456789abcdef"
+	remote := &cache.HTTP{URL: ts.URL}
+
+	_, err = remote.Get(key)
+	assert.Equal(t, cache.ErrNotFound, err)
+
+	assert.NoError(t, remote.Put(key, bytes.NewBufferString("content")))
+	r, err := remote.Get(key)
+	assert.NoError(t, err)
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, "content", string(b))
+
+	assert.Error(t, remote.Put("../escape", bytes.NewBufferString("content")), "invalid keys should be rejected")
+
+	server.ReadOnly = true
+	assert.Error(t, remote.Put(key, bytes.NewBufferString("other")), "read-only server should reject uploads")
+}
+
+func TestHTTPTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	remote := &cache.HTTP{URL: ts.URL, Timeout: 50 * time.Millisecond}
+	_, err := remote.Get("0123456789abcdef")
+	assert.Equal(t, cache.ErrNotFound, err, "a timeout should be a cache miss")
+}
+
+func TestLayered(t *testing.T) {
+	localDir, err := ioutil.TempDir("", "task-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(localDir)
+	remoteDir, err := ioutil.TempDir("", "task-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(remoteDir)
+
+	local := &cache.Local{Dir: localDir}
+	remote := &cache.Local{Dir: remoteDir}
+	layered := &cache.Layered{Local: local, Remote: remote}
+
+	assert.NoError(t, remote.Put("aaaa", bytes.NewBufferString("from remote")))
+	r, err := layered.Get("aaaa")
+	assert.NoError(t, err)
+	r.Close()
+	r, err = local.Get("aaaa")
+	assert.NoError(t, err, "remote hits should be stored locally")
+	r.Close()
+
+	assert.NoError(t, layered.Put("bbbb", bytes.NewBufferString("uploaded")))
+	r, err = remote.Get("bbbb")
+	assert.NoError(t, err)
+	r.Close()
+
+	layered.ReadOnly = true
+	assert.NoError(t, layered.Put("cccc", bytes.NewBufferString("not uploaded")))
+	_, err = remote.Get("cccc")
+	assert.Equal(t, cache.ErrNotFound, err)
+}
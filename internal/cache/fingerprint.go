@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/leiyangyou/task/v2/internal/status"
+	"github.com/leiyangyou/task/v2/internal/taskfile"
+)
+
+// Fingerprint identifies the output of a compiled task. It covers the
+// commands (so vars used on them are accounted for), the environment and
+// the content of the source files, but not the task directory, so it's
+// stable across checkouts and machines.
+func Fingerprint(t *taskfile.Task) (string, error) {
+	h := sha256.New()
+
+	fmt.Fprintf(h, "task\x00%s\x00", t.Task)
+	for _, c := range t.Cmds {
+		fmt.Fprintf(h, "cmd\x00%s\x00%s\x00", c.Cmd, c.Task)
+		writeVars(h, c.Vars)
+	}
+	writeVars(h, t.Env)
+	for _, g := range t.Generates {
+		fmt.Fprintf(h, "generates\x00%s\x00", g)
+	}
+
+	dir, err := filepath.Abs(t.Dir)
+	if err != nil {
+		return "", err
+	}
+	sources, err := status.Glob(dir, t.Sources)
+	if err != nil {
+		return "", err
+	}
+	for _, s := range sources {
+		info, err := os.Stat(s)
+		if err != nil {
+			return "", err
+		}
+		if info.IsDir() {
+			continue
+		}
+		rel, err := filepath.Rel(dir, s)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "source\x00%s\x00", filepath.ToSlash(rel))
+		if err = copyFile(h, s); err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func writeVars(w io.Writer, vars taskfile.Vars) {
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "var\x00%s\x00%s\x00%s\x00", k, vars[k].Static, vars[k].Sh)
+	}
+}
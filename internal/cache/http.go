@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var _ Store = &HTTP{}
+
+// DefaultHTTPTimeout is the Timeout of HTTP when not given
+const DefaultHTTPTimeout = 30 * time.Second
+
+// HTTP is a Store backed by a remote server speaking a simple protocol:
+// entries are read with GET and written with PUT on <URL>/<key>
+type HTTP struct {
+	URL string
+	// Timeout limits each request, including reading its body. Ignored
+	// if Client is given
+	Timeout time.Duration
+	Client  *http.Client
+}
+
+// Get implements the Store interface. A request that times out is
+// reported as ErrNotFound, so a slow server is a cache miss
+func (h *HTTP) Get(key string) (io.ReadCloser, error) {
+	resp, err := h.client().Get(h.url(key))
+	if err != nil {
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf(`task: remote cache returned "%s" for GET %s`, resp.Status, key)
+	}
+}
+
+// Put implements the Store interface
+func (h *HTTP) Put(key string, r io.Reader) error {
+	req, err := http.NewRequest(http.MethodPut, h.url(key), r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := h.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf(`task: remote cache returned "%s" for PUT %s`, resp.Status, key)
+	}
+	return nil
+}
+
+func (h *HTTP) client() *http.Client {
+	if h.Client != nil {
+		return h.Client
+	}
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+func (h *HTTP) url(key string) string {
+	return strings.TrimSuffix(h.URL, "/") + "/" + key
+}
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"io"
+)
+
+var _ Store = &Layered{}
+
+// Layered is a Store that looks for entries on a local store first, and
+// then on a remote one. Remote hits are copied to the local store.
+type Layered struct {
+	Local  Store
+	Remote Store
+	// ReadOnly disables uploads to the remote store
+	ReadOnly bool
+}
+
+// Get implements the Store interface
+func (l *Layered) Get(key string) (io.ReadCloser, error) {
+	r, err := l.Local.Get(key)
+	if err != ErrNotFound {
+		return r, err
+	}
+
+	r, err = l.Remote.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	err = l.Local.Put(key, r)
+	r.Close()
+	if err != nil {
+		return nil, err
+	}
+	return l.Local.Get(key)
+}
+
+// Put implements the Store interface
+func (l *Layered) Put(key string, r io.Reader) error {
+	if err := l.Local.Put(key, r); err != nil {
+		return err
+	}
+	if l.ReadOnly {
+		return nil
+	}
+
+	local, err := l.Local.Get(key)
+	if err != nil {
+		return err
+	}
+	defer local.Close()
+	return l.Remote.Put(key, local)
+}
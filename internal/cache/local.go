@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var _ Store = &Local{}
+
+// Local is a Store backed by a directory on the file system
+type Local struct {
+	Dir string
+}
+
+// Get implements the Store interface
+func (l *Local) Get(key string) (io.ReadCloser, error) {
+	f, err := os.Open(l.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// Put implements the Store interface. The entry is written to a temporary
+// file first, so concurrent readers never see a partial entry.
+func (l *Local) Put(key string, r io.Reader) error {
+	path := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (l *Local) path(key string) string {
+	if len(key) < 2 {
+		return filepath.Join(l.Dir, key)
+	}
+	return filepath.Join(l.Dir, key[:2], key)
+}
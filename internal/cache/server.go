@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var keyRegexp = regexp.MustCompile("^[0-9a-f]{2,}$")
+
+// Server serves a Store over HTTP, to be used as a remote cache by HTTP
+type Server struct {
+	Store Store
+	// ReadOnly makes the server reject uploads
+	ReadOnly bool
+}
+
+// ServeHTTP implements the http.Handler interface
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	if !keyRegexp.MatchString(key) {
+		http.Error(w, "invalid cache key", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		rc, err := s.Store.Get(key)
+		if err == ErrNotFound {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer rc.Close()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if r.Method == http.MethodGet {
+			_, _ = io.Copy(w, rc)
+		}
+	case http.MethodPut:
+		if s.ReadOnly {
+			http.Error(w, "cache is read-only", http.StatusForbidden)
+			return
+		}
+		if err := s.Store.Put(key, r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
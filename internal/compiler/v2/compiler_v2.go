@@ -20,7 +20,9 @@ type CompilerV2 struct {
 	Dir string
 
 	Taskvars     taskfile.Vars
+	Dotenv       taskfile.Vars
 	TaskfileVars taskfile.Vars
+	ProfileVars  taskfile.Vars
 
 	Expansions int
 
@@ -39,12 +41,14 @@ func (c *CompilerV2) Reset() {
 // GetVariables returns fully resolved variables following the priority order:
 // 1. Task variables
 // 2. Call variables
-// 3. Taskfile variables
-// 4. Taskvars file variables
-// 5. Environment variables
+// 3. Profile variables
+// 4. Taskfile variables
+// 5. Dotenv file variables
+// 6. Taskvars file variables
+// 7. Environment variables
 func (c *CompilerV2) GetVariables(t *taskfile.Task, call taskfile.Call) (taskfile.Vars, error) {
 	vr := varResolver{c: c, vars: compiler.GetEnviron()}
-	for _, vars := range []taskfile.Vars{c.Taskvars, t.TaskfileVars, call.Vars, t.Vars} {
+	for _, vars := range []taskfile.Vars{c.Taskvars, c.Dotenv, t.TaskfileVars, c.ProfileVars, call.Vars, t.Vars} {
 		for i := 0; i < c.Expansions; i++ {
 			vr.merge(vars)
 		}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"mvdan.cc/sh/expand"
 	"mvdan.cc/sh/interp"
@@ -22,6 +23,13 @@ type RunCommandOptions struct {
 	Stdin   io.Reader
 	Stdout  io.Writer
 	Stderr  io.Writer
+
+	// Signal, if set, makes each program run on its own process group,
+	// which receives it when the context is cancelled. The whole group is
+	// killed if still running after KillTimeout, and RunCommand only
+	// returns after the group stopped
+	Signal      os.Signal
+	KillTimeout time.Duration
 }
 
 var (
@@ -29,6 +37,9 @@ var (
 	ErrNilOptions = errors.New("execext: nil options given")
 )
 
+// DefaultKillTimeout is the time given to programs to stop after Signal
+const DefaultKillTimeout = 2 * time.Second
+
 // RunCommand runs a shell command
 func RunCommand(ctx context.Context, opts *RunCommandOptions) error {
 	if opts == nil {
@@ -45,11 +56,20 @@ func RunCommand(ctx context.Context, opts *RunCommandOptions) error {
 		environ = os.Environ()
 	}
 
+	exec := interp.DefaultExec
+	if opts.Signal != nil {
+		killTimeout := opts.KillTimeout
+		if killTimeout <= 0 {
+			killTimeout = DefaultKillTimeout
+		}
+		exec = groupExec(opts.Signal, killTimeout)
+	}
+
 	r, err := interp.New(
 		interp.Dir(opts.Dir),
 		interp.Env(expand.ListEnviron(environ...)),
 
-		interp.Module(interp.DefaultExec),
+		interp.Module(exec),
 		interp.Module(interp.OpenDevImpls(interp.DefaultOpen)),
 
 		interp.StdIO(opts.Stdin, opts.Stdout, opts.Stderr),
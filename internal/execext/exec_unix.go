@@ -0,0 +1,116 @@
+//go:build !windows
+// +build !windows
+
+package execext
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"mvdan.cc/sh/expand"
+	"mvdan.cc/sh/interp"
+)
+
+// groupExec works like interp.DefaultExec, but runs each program on its
+// own process group, so processes spawned by it are stopped as well
+func groupExec(sig os.Signal, killTimeout time.Duration) interp.ModuleExec {
+	return func(ctx context.Context, path string, args []string) error {
+		mc, _ := interp.FromModuleContext(ctx)
+		if path == "" {
+			fmt.Fprintf(mc.Stderr, "%q: executable file not found in $PATH\n", args[0])
+			return interp.ExitStatus(127)
+		}
+		cmd := exec.Cmd{
+			Path:        path,
+			Args:        args,
+			Env:         execEnv(mc.Env),
+			Dir:         mc.Dir,
+			Stdin:       mc.Stdin,
+			Stdout:      mc.Stdout,
+			Stderr:      mc.Stderr,
+			SysProcAttr: &syscall.SysProcAttr{Setpgid: true},
+		}
+
+		err := cmd.Start()
+		if err == nil {
+			exited := make(chan struct{})
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				select {
+				case <-ctx.Done():
+					stopGroup(cmd.Process.Pid, sig, killTimeout, exited)
+				case <-exited:
+				}
+			}()
+
+			err = cmd.Wait()
+			close(exited)
+			<-stopped
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		switch x := err.(type) {
+		case *exec.ExitError:
+			if status, ok := x.Sys().(syscall.WaitStatus); ok {
+				return interp.ExitStatus(status.ExitStatus())
+			}
+			return interp.ExitStatus(1)
+		case *exec.Error:
+			// did not start
+			fmt.Fprintf(mc.Stderr, "%v\n", err)
+			return interp.ExitStatus(127)
+		default:
+			return err
+		}
+	}
+}
+
+// stopGroup sends sig to the process group led by pid, and waits for the
+// leader and then the rest of the group to exit. Whatever is still running
+// after killTimeout is killed.
+func stopGroup(pid int, sig os.Signal, killTimeout time.Duration, exited <-chan struct{}) {
+	deadline := time.After(killTimeout)
+	kill := func() { _ = syscall.Kill(-pid, syscall.SIGKILL) }
+
+	if s, ok := sig.(syscall.Signal); ok {
+		_ = syscall.Kill(-pid, s)
+	} else {
+		kill()
+	}
+
+	select {
+	case <-exited:
+	case <-deadline:
+		kill()
+		return
+	}
+
+	// children may outlive the leader, e.g. when it doesn't forward the
+	// signal to them
+	for syscall.Kill(-pid, 0) == nil {
+		select {
+		case <-deadline:
+			kill()
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func execEnv(env expand.Environ) []string {
+	list := make([]string, 0, 32)
+	env.Each(func(name string, vr expand.Variable) bool {
+		if vr.Exported {
+			list = append(list, name+"="+vr.String())
+		}
+		return true
+	})
+	return list
+}
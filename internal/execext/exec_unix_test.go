@@ -0,0 +1,101 @@
+//go:build !windows
+// +build !windows
+
+package execext_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/leiyangyou/task/v2/internal/execext"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCommandStopsProcessGroup(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+	}{
+		{"signal", `sh -c 'sleep 30 & echo $! > pid; wait'`},
+		{"ignored signal", `sh -c 'trap "" TERM; sleep 30 & echo $! > pid; wait'`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "task-execext")
+			assert.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			errc := make(chan error)
+			go func() {
+				errc <- execext.RunCommand(ctx, &execext.RunCommandOptions{
+					Command:     test.command,
+					Dir:         dir,
+					Stdout:      ioutil.Discard,
+					Stderr:      ioutil.Discard,
+					Signal:      syscall.SIGTERM,
+					KillTimeout: 200 * time.Millisecond,
+				})
+			}()
+
+			pid := waitForPid(t, filepath.Join(dir, "pid"))
+			cancel()
+
+			select {
+			case err := <-errc:
+				assert.Equal(t, context.Canceled, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("command was not stopped")
+			}
+			// a killed process may take a moment to exit
+			for i := 0; i < 100 && isRunning(pid); i++ {
+				time.Sleep(10 * time.Millisecond)
+			}
+			assert.False(t, isRunning(pid), "child process should have been stopped")
+		})
+	}
+}
+
+func TestParseSignal(t *testing.T) {
+	for _, name := range []string{"SIGTERM", "TERM", "sigterm"} {
+		sig, err := execext.ParseSignal(name)
+		assert.NoError(t, err)
+		assert.Equal(t, syscall.SIGTERM, sig)
+	}
+	_, err := execext.ParseSignal("SIGFOO")
+	assert.Error(t, err)
+}
+
+func waitForPid(t *testing.T, file string) int {
+	for i := 0; i < 500; i++ {
+		data, err := ioutil.ReadFile(file)
+		if pid, err2 := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && err2 == nil {
+			return pid
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("command did not start")
+	return 0
+}
+
+// isRunning ignores zombies, which are not reaped by some container inits
+func isRunning(pid int) bool {
+	if syscall.Kill(pid, 0) != nil {
+		return false
+	}
+	stat, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return true
+	}
+	fields := strings.Fields(string(stat))
+	return len(fields) < 3 || fields[2] != "Z"
+}
@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package execext
+
+import (
+	"os"
+	"time"
+
+	"mvdan.cc/sh/interp"
+)
+
+// groupExec falls back to the default behavior of killing the program,
+// since Windows has neither process groups nor signals other than kill
+func groupExec(sig os.Signal, killTimeout time.Duration) interp.ModuleExec {
+	return interp.DefaultExec
+}
@@ -0,0 +1,25 @@
+package execext
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+)
+
+var signals = map[string]os.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"TERM": syscall.SIGTERM,
+	"KILL": syscall.SIGKILL,
+}
+
+// ParseSignal returns the signal of the given name, e.g. "SIGTERM" or "TERM"
+func ParseSignal(name string) (os.Signal, error) {
+	sig, ok := signals[strings.TrimPrefix(strings.ToUpper(name), "SIG")]
+	if !ok {
+		return nil, fmt.Errorf(`execext: unknown signal "%s"`, name)
+	}
+	return sig, nil
+}
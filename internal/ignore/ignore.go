@@ -0,0 +1,154 @@
+// Package ignore implements matching of paths against gitignore-style
+// patterns.
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar"
+)
+
+type pattern struct {
+	glob     string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Matcher matches paths against a list of gitignore-style patterns, which
+// are relative to a base directory
+type Matcher struct {
+	base     string
+	patterns []pattern
+}
+
+// New returns a Matcher for the given patterns
+func New(base string, patterns []string) *Matcher {
+	m := &Matcher{base: base}
+	for _, p := range patterns {
+		m.add(p)
+	}
+	return m
+}
+
+// Read returns a Matcher for the patterns of a .gitignore file, relative
+// to the directory of the file
+func Read(file string) (*Matcher, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &Matcher{base: filepath.Dir(file)}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m.add(scanner.Text())
+	}
+	return m, scanner.Err()
+}
+
+func (m *Matcher) add(line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	var p pattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		p.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return
+	}
+	p.glob = line
+	m.patterns = append(m.patterns, p)
+}
+
+// Match reports whether path is matched by any of the patterns and, if so,
+// whether it's ignored, i.e. the last matching pattern is not a negation.
+// Parent directories of path are not considered.
+func (m *Matcher) Match(path string, isDir bool) (matched bool, ignored bool) {
+	if m == nil {
+		return false, false
+	}
+	rel, ok := relative(m.base, path)
+	if !ok {
+		return false, false
+	}
+
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		name := rel
+		if !p.anchored {
+			name = filepath.Base(filepath.FromSlash(rel))
+		}
+		if ok, _ := doublestar.Match(p.glob, name); ok {
+			matched, ignored = true, !p.negate
+		}
+	}
+	return
+}
+
+// Ignored reports whether path, or any of its parent directories below the
+// base directory, is ignored. The file system is checked to know whether
+// path is a directory
+func (m *Matcher) Ignored(path string) bool {
+	return m.ignored(path, isDir)
+}
+
+// IgnoredPath is like Ignored, but is told whether path is a directory, so
+// it doesn't touch the file system
+func (m *Matcher) IgnoredPath(path string, dir bool) bool {
+	return m.ignored(path, func(string) bool { return dir })
+}
+
+func (m *Matcher) ignored(path string, isDir func(string) bool) bool {
+	if m == nil || len(m.patterns) == 0 {
+		return false
+	}
+	rel, ok := relative(m.base, path)
+	if !ok {
+		return false
+	}
+
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		sub := filepath.Join(m.base, filepath.FromSlash(strings.Join(parts[:i+1], "/")))
+		if _, ignored := m.Match(sub, i < len(parts)-1 || isDir(sub)); ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// relative returns the slash separated path of path relative to base, and
+// false if path is not inside base
+func relative(base, path string) (string, bool) {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
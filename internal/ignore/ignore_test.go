@@ -0,0 +1,113 @@
+package ignore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/leiyangyou/task/v2/internal/ignore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcher(t *testing.T) {
+	base := filepath.FromSlash("/project")
+	m := ignore.New(base, []string{
+		"# comment",
+		"*.swp",
+		"dist/",
+		"/build",
+		"docs/**/*.html",
+		"!docs/index.html",
+		"vendor/",
+		"!vendor/",
+	})
+
+	tests := []struct {
+		Path    string
+		Ignored bool
+	}{
+		{"main.go", false},
+		{".main.go.swp", true},
+		{"sub/.main.go.swp", true},
+		{"dist/app.js", true},
+		{"sub/dist/app.js", true},
+		{"dist", false}, // not a directory
+		{"build/out", true},
+		{"sub/build/out", false},
+		{"docs/a/b.html", true},
+		{"docs/index.html", false},
+		{"vendor/lib.go", false},
+		{"../outside.swp", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.Ignored, m.Ignored(filepath.Join(base, filepath.FromSlash(test.Path))), test.Path)
+	}
+
+	// without checking the file system
+	assert.True(t, m.IgnoredPath(filepath.Join(base, "dist"), true))
+	assert.False(t, m.IgnoredPath(filepath.Join(base, "dist"), false))
+	assert.True(t, m.IgnoredPath(filepath.Join(base, "dist", "app.js"), false))
+}
+
+func TestTree(t *testing.T) {
+	root, err := ioutil.TempDir("", "task-ignore")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	write := func(name, content string) {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	write(".gitignore", "*.log\ntmp/\n")
+	write("sub/.gitignore", "!keep.log\ngenerated.go\n")
+
+	tree := &ignore.Tree{Root: root}
+	tests := []struct {
+		Path    string
+		Ignored bool
+	}{
+		{"main.go", false},
+		{"debug.log", true},
+		{"sub/debug.log", true},
+		{"sub/keep.log", false},
+		{"keep.log", true},
+		{"tmp/file", true},
+		{"sub/generated.go", true},
+		{"generated.go", false},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.Ignored, tree.Ignored(filepath.Join(root, filepath.FromSlash(test.Path))), test.Path)
+	}
+
+	write("sub/.gitignore", "")
+	assert.True(t, tree.Ignored(filepath.Join(root, "sub", "generated.go")), "parsed files should be cached")
+	tree.Forget(filepath.Join(root, "sub"))
+	assert.False(t, tree.Ignored(filepath.Join(root, "sub", "generated.go")))
+}
+
+func TestTreeOverrides(t *testing.T) {
+	root, err := ioutil.TempDir("", "task-ignore")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, ".gitignore"), []byte("*.log\nbuild/\n"), 0644))
+
+	tree := &ignore.Tree{Root: root, Overrides: ignore.New(root, []string{"!keep.log", "*.tmp", "!build/", "build/*.o"})}
+	tests := []struct {
+		Path    string
+		Ignored bool
+	}{
+		{"debug.log", true},
+		{"keep.log", false},
+		{"file.tmp", true},
+		{"main.go", false},
+		{"build/app", false},
+		{"build/app.o", true},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.Ignored, tree.IgnoredPath(filepath.Join(root, filepath.FromSlash(test.Path)), false), test.Path)
+	}
+}
@@ -0,0 +1,85 @@
+package ignore
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Tree matches paths against all the .gitignore files found from a root
+// directory down to the directory of the path, like git does. Parsed files
+// are cached until Forget is called.
+type Tree struct {
+	Root string
+	// Overrides are matched after the .gitignore files, so its patterns
+	// have precedence over them, e.g. a negation un-ignores a path
+	Overrides *Matcher
+
+	mu       sync.Mutex
+	matchers map[string]*Matcher
+}
+
+// Ignored reports whether path is ignored by the .gitignore files of the
+// tree. The file system is checked to know whether path is a directory
+func (t *Tree) Ignored(path string) bool {
+	return t.ignored(path, isDir)
+}
+
+// IgnoredPath is like Ignored, but is told whether path is a directory. Only
+// the .gitignore files are read from the file system
+func (t *Tree) IgnoredPath(path string, dir bool) bool {
+	return t.ignored(path, func(string) bool { return dir })
+}
+
+func (t *Tree) ignored(path string, isDir func(string) bool) bool {
+	rel, ok := relative(t.Root, path)
+	if !ok {
+		return false
+	}
+
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		sub := filepath.Join(t.Root, filepath.FromSlash(strings.Join(parts[:i+1], "/")))
+		dir := i < len(parts)-1 || isDir(sub)
+
+		// deeper .gitignore files have precedence over the upper ones
+		ignored := false
+		for j := 0; j <= i; j++ {
+			m := t.matcher(filepath.Join(t.Root, filepath.FromSlash(strings.Join(parts[:j], "/"))))
+			if matched, ign := m.Match(sub, dir); matched {
+				ignored = ign
+			}
+		}
+		if matched, ign := t.Overrides.Match(sub, dir); matched {
+			ignored = ign
+		}
+		if ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// Forget drops the cached .gitignore of a directory, so it's read again
+func (t *Tree) Forget(dir string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.matchers, dir)
+}
+
+func (t *Tree) matcher(dir string) *Matcher {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if m, ok := t.matchers[dir]; ok {
+		return m
+	}
+	if t.matchers == nil {
+		t.matchers = make(map[string]*Matcher)
+	}
+
+	// a missing or unreadable file is a nil Matcher, which matches nothing
+	m, _ := Read(filepath.Join(dir, ".gitignore"))
+	t.matchers[dir] = m
+	return m
+}
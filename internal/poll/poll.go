@@ -0,0 +1,222 @@
+// Package poll implements a file watcher that scans the file system on an
+// interval. It is slower than native notifications, but works on network
+// file systems and container bind mounts where those are not delivered.
+package poll
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Op is the kind of change of an Event
+type Op int
+
+const (
+	// Create is sent when a path appears
+	Create Op = iota
+	// Write is sent when the size, mode or modification time of a path
+	// changes
+	Write
+	// Remove is sent when a path disappears
+	Remove
+)
+
+func (op Op) String() string {
+	switch op {
+	case Create:
+		return "create"
+	case Write:
+		return "write"
+	case Remove:
+		return "remove"
+	}
+	return "unknown"
+}
+
+// Event is a change found between two scans
+type Event struct {
+	Op   Op
+	Path string
+}
+
+// Watcher polls a set of paths for changes
+type Watcher struct {
+	// Events receives the changes found on each scan. It's closed by Close
+	Events chan Event
+
+	clock  Clock
+	ignore func(path string, isDir bool) bool
+
+	mu       sync.Mutex
+	interval time.Duration
+	paths    []watchPath
+	state    map[string]fileState
+
+	started bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type watchPath struct {
+	path      string
+	recursive bool
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+	mode    os.FileMode
+}
+
+// New returns a Watcher scanning every interval. A nil clock means
+// RealClock, and ignore, if given, prevents paths (and the content of
+// directories) from being scanned
+func New(interval time.Duration, clock Clock, ignore func(path string, isDir bool) bool) *Watcher {
+	if clock == nil {
+		clock = RealClock{}
+	}
+	return &Watcher{
+		Events:   make(chan Event, 30),
+		interval: interval,
+		clock:    clock,
+		ignore:   ignore,
+		state:    make(map[string]fileState),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Add watches a path using the same notation of rjeczalik/notify: a path
+// ending with "/..." is watched recursively, a directory is watched with
+// its direct children, and a file is watched alone. The path is scanned
+// right away, so only changes made after Add returns are reported.
+func (w *Watcher) Add(path string) error {
+	recursive := false
+	if filepath.Base(path) == "..." {
+		recursive = true
+		path = filepath.Dir(path)
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if _, err = os.Stat(path); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	p := watchPath{path: path, recursive: recursive}
+	w.paths = append(w.paths, p)
+	w.scanPath(p, w.state)
+	return nil
+}
+
+// SetInterval changes the interval, starting from the next scan
+func (w *Watcher) SetInterval(interval time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.interval = interval
+}
+
+func (w *Watcher) currentInterval() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.interval
+}
+
+// Start scans the watched paths on every interval until Close is called
+func (w *Watcher) Start() {
+	w.started = true
+	go func() {
+		defer close(w.done)
+		for {
+			select {
+			case <-w.clock.After(w.currentInterval()):
+				for _, event := range w.Scan() {
+					select {
+					case w.Events <- event:
+					case <-w.stop:
+						return
+					}
+				}
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the watcher and closes Events
+func (w *Watcher) Close() {
+	close(w.stop)
+	if w.started {
+		<-w.done
+	}
+	close(w.Events)
+}
+
+// Scan walks the watched paths once and returns the changes since the
+// previous scan, sorted by path
+func (w *Watcher) Scan() []Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	state := make(map[string]fileState, len(w.state))
+	for _, p := range w.paths {
+		w.scanPath(p, state)
+	}
+
+	var events []Event
+	for path, s := range state {
+		previous, ok := w.state[path]
+		switch {
+		case !ok:
+			events = append(events, Event{Op: Create, Path: path})
+		case !previous.modTime.Equal(s.modTime) || previous.size != s.size || previous.mode != s.mode:
+			events = append(events, Event{Op: Write, Path: path})
+		}
+	}
+	for path := range w.state {
+		if _, ok := state[path]; !ok {
+			events = append(events, Event{Op: Remove, Path: path})
+		}
+	}
+	w.state = state
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Path < events[j].Path
+	})
+	return events
+}
+
+func (w *Watcher) scanPath(p watchPath, state map[string]fileState) {
+	_ = filepath.Walk(p.path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// the path may have been removed while walking
+			return nil
+		}
+		if path != p.path && w.ignore != nil && w.ignore(path, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		s := fileState{modTime: info.ModTime(), size: info.Size(), mode: info.Mode()}
+		if info.IsDir() {
+			// only the content of directories is interesting
+			s.modTime, s.size = time.Time{}, 0
+		}
+		state[path] = s
+
+		if info.IsDir() && path != p.path && !p.recursive {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
@@ -0,0 +1,134 @@
+package poll_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/leiyangyou/task/v2/internal/poll"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	assert.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestScan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "task-poll")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	past := time.Now().Add(-time.Hour)
+	writeFile(t, filepath.Join(dir, "a.txt"), "a", past)
+	writeFile(t, filepath.Join(dir, "sub", "b.txt"), "b", past)
+	writeFile(t, filepath.Join(dir, "skip", "c.txt"), "c", past)
+	writeFile(t, filepath.Join(dir, "flat", "d.txt"), "d", past)
+	writeFile(t, filepath.Join(dir, "flat", "deep", "e.txt"), "e", past)
+
+	w := poll.New(time.Second, nil, func(path string, isDir bool) bool {
+		return isDir && filepath.Base(path) == "skip"
+	})
+	assert.NoError(t, w.Add(filepath.Join(dir, "...")))
+	assert.NoError(t, w.Add(filepath.Join(dir, "flat")))
+	assert.Error(t, w.Add(filepath.Join(dir, "missing")))
+
+	assert.Empty(t, w.Scan())
+
+	writeFile(t, filepath.Join(dir, "a.txt"), "a", past.Add(time.Second))
+	assert.NoError(t, os.Remove(filepath.Join(dir, "sub", "b.txt")))
+	writeFile(t, filepath.Join(dir, "sub", "new.txt"), "new", past)
+	writeFile(t, filepath.Join(dir, "skip", "c.txt"), "changed", past)
+
+	assert.Equal(t, []poll.Event{
+		{Op: poll.Write, Path: filepath.Join(dir, "a.txt")},
+		{Op: poll.Remove, Path: filepath.Join(dir, "sub", "b.txt")},
+		{Op: poll.Create, Path: filepath.Join(dir, "sub", "new.txt")},
+	}, w.Scan())
+	assert.Empty(t, w.Scan())
+}
+
+func TestScanNonRecursive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "task-poll")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	past := time.Now().Add(-time.Hour)
+	writeFile(t, filepath.Join(dir, "a.txt"), "a", past)
+	writeFile(t, filepath.Join(dir, "deep", "b.txt"), "b", past)
+
+	w := poll.New(time.Second, nil, nil)
+	assert.NoError(t, w.Add(dir+string(filepath.Separator)))
+
+	writeFile(t, filepath.Join(dir, "a.txt"), "changed", past)
+	writeFile(t, filepath.Join(dir, "deep", "b.txt"), "changed", past)
+
+	assert.Equal(t, []poll.Event{
+		{Op: poll.Write, Path: filepath.Join(dir, "a.txt")},
+	}, w.Scan())
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "task-poll")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	clock := &poll.FakeClock{}
+	w := poll.New(time.Second, clock, nil)
+	assert.NoError(t, w.Add(filepath.Join(dir, "...")))
+	w.Start()
+	defer w.Close()
+
+	waitForScan := func() {
+		for clock.Waiters() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitForScan()
+	writeFile(t, filepath.Join(dir, "a.txt"), "a", time.Now())
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case event := <-w.Events:
+		t.Fatalf("unexpected event before the interval: %v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case event := <-w.Events:
+		assert.Equal(t, poll.Create, event.Op)
+		assert.True(t, strings.HasSuffix(event.Path, "a.txt"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event after the interval")
+	}
+
+	waitForScan()
+	clock.Advance(time.Second)
+	waitForScan()
+	select {
+	case event := <-w.Events:
+		t.Fatalf("unexpected event without changes: %v", event)
+	default:
+	}
+
+	// the new interval is used from the scan after the pending one
+	w.SetInterval(100 * time.Millisecond)
+	clock.Advance(time.Second)
+	waitForScan()
+	writeFile(t, filepath.Join(dir, "b.txt"), "b", time.Now())
+	clock.Advance(100 * time.Millisecond)
+	select {
+	case event := <-w.Events:
+		assert.Equal(t, poll.Create, event.Op)
+		assert.True(t, strings.HasSuffix(event.Path, "b.txt"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event after the new interval")
+	}
+}
@@ -0,0 +1,93 @@
+package remote
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// LockFile is the name of the lock file, next to the Taskfile
+const LockFile = "Taskfile.lock"
+
+// Lock pins the checksums of remote Taskfiles by URL. The first checksum
+// seen for a URL is recorded, and any other one is an error until the
+// entry is removed from the file
+type Lock struct {
+	path     string
+	includes map[string]lockEntry
+	changed  bool
+}
+
+type lockFile struct {
+	Includes map[string]lockEntry
+}
+
+type lockEntry struct {
+	Checksum string
+}
+
+// ReadLock reads a lock file. A missing file is an empty lock
+func ReadLock(path string) (*Lock, error) {
+	l := &Lock{path: path, includes: make(map[string]lockEntry)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file lockFile
+	if err = yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("task: unable to deserialize file %v due to: %v", path, err)
+	}
+	for url, entry := range file.Includes {
+		l.includes[url] = entry
+	}
+	return l, nil
+}
+
+// Save writes the lock file if new checksums were recorded
+func (l *Lock) Save() error {
+	if l == nil || !l.changed {
+		return nil
+	}
+	data, err := yaml.Marshal(lockFile{Includes: l.includes})
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(l.path, data, 0644); err != nil {
+		return err
+	}
+	l.changed = false
+	return nil
+}
+
+func (l *Lock) matches(url, checksum string) bool {
+	if l == nil {
+		return true
+	}
+	entry, ok := l.includes[url]
+	return ok && entry.Checksum == checksum
+}
+
+// verify records the checksum of a URL if it's new, or fails if it
+// doesn't match the recorded one
+func (l *Lock) verify(url, checksum string) error {
+	if l == nil {
+		return nil
+	}
+	entry, ok := l.includes[url]
+	if !ok {
+		l.includes[url] = lockEntry{Checksum: checksum}
+		l.changed = true
+		return nil
+	}
+	if entry.Checksum != checksum {
+		return fmt.Errorf(`task: Checksum of "%s" doesn't match %s: expected %s, got %s. Remove its entry to accept the change`, url, l.path, entry.Checksum, checksum)
+	}
+	return nil
+}
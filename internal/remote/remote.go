@@ -0,0 +1,217 @@
+// Package remote fetches Taskfiles included from git repositories or HTTP
+// servers. They are kept on a local cache, keyed by URL and ref, and their
+// checksums are pinned on a lock file, so a Taskfile changing upstream is
+// noticed instead of silently running different commands.
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/leiyangyou/task/v2/internal/taskfile"
+)
+
+// IsRemote reports whether an include path is the URL of a remote Taskfile
+func IsRemote(path string) bool {
+	return strings.HasPrefix(path, "git+") ||
+		strings.HasPrefix(path, "https://") ||
+		strings.HasPrefix(path, "http://")
+}
+
+// Fetcher fetches remote Taskfiles into a cache directory
+type Fetcher struct {
+	// Dir is the cache directory. Fetch fails if it's empty
+	Dir string
+	// Offline makes Fetch use only the cache
+	Offline bool
+	// Lock pins the checksums of the fetched Taskfiles. A nil Lock pins
+	// nothing
+	Lock   *Lock
+	Client *http.Client
+}
+
+// source is a parsed remote Taskfile URL
+type source struct {
+	git bool
+	// url is the URL of the repository or of the file
+	url string
+	// path is the path of the Taskfile on the repository
+	path string
+	ref  string
+}
+
+// parse parses URLs like https://example.com/Taskfile.yml, or, for git,
+// git+https://example.com/repo.git//path/on/repo#ref, where the path
+// defaults to the Taskfile of the root of the repository, and the ref to
+// its default branch
+func parse(rawurl string) (source, error) {
+	if !strings.HasPrefix(rawurl, "git+") {
+		if strings.Contains(rawurl, "#") {
+			return source{}, fmt.Errorf(`task: Refs are only supported on git includes, got "%s"`, rawurl)
+		}
+		return source{url: rawurl}, nil
+	}
+
+	s := source{git: true, url: strings.TrimPrefix(rawurl, "git+")}
+	if i := strings.LastIndex(s.url, "#"); i != -1 {
+		s.url, s.ref = s.url[:i], s.url[i+1:]
+	}
+	schemeEnd := strings.Index(s.url, "://")
+	if schemeEnd == -1 {
+		return source{}, fmt.Errorf(`task: Invalid git include "%s"`, rawurl)
+	}
+	if i := strings.Index(s.url[schemeEnd+3:], "//"); i != -1 {
+		i += schemeEnd + 3
+		s.url, s.path = s.url[:i], s.url[i+2:]
+	}
+	return s, nil
+}
+
+// Fetch returns the path of the cached copy of a remote Taskfile, fetching
+// it unless it's cached and matches the lock
+func (f *Fetcher) Fetch(rawurl string) (string, error) {
+	s, err := parse(rawurl)
+	if err != nil {
+		return "", err
+	}
+	if f.Dir == "" {
+		return "", fmt.Errorf(`task: Unable to include "%s": no cache directory found, set TASK_CACHE_DIR`, rawurl)
+	}
+	dir := filepath.Join(f.Dir, cacheKey(rawurl))
+
+	if path, err := s.taskfile(dir); err == nil {
+		if sum, err := checksum(path); err == nil && (f.Offline || f.Lock.matches(rawurl, sum)) {
+			return path, f.Lock.verify(rawurl, sum)
+		}
+	}
+	if f.Offline {
+		return "", fmt.Errorf(`task: "%s" is not cached, and can't be fetched offline`, rawurl)
+	}
+
+	// fetch to a temporary directory, so an interrupted fetch or a
+	// Taskfile not matching the lock never replaces the cached copy
+	if err = os.MkdirAll(f.Dir, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempDir(f.Dir, ".tmp-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	if s.git {
+		err = fetchGit(s, tmp)
+	} else {
+		err = f.fetchHTTP(s, tmp)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	path, err := s.taskfile(tmp)
+	if err != nil {
+		return "", err
+	}
+	sum, err := checksum(path)
+	if err != nil {
+		return "", err
+	}
+	if err = f.Lock.verify(rawurl, sum); err != nil {
+		return "", err
+	}
+
+	if err = os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	if err = os.Rename(tmp, dir); err != nil {
+		return "", err
+	}
+	return s.taskfile(dir)
+}
+
+// taskfile returns the path of the Taskfile on the cache directory of s
+func (s source) taskfile(dir string) (string, error) {
+	if !s.git {
+		return filepath.Join(dir, "Taskfile.yml"), nil
+	}
+	path := filepath.Join(dir, filepath.FromSlash(s.path))
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		found, ok := taskfile.FindInDir(path)
+		if !ok {
+			return "", fmt.Errorf(`task: No Taskfile found on "%s" of "%s"`, s.path, s.url)
+		}
+		path = found
+	}
+	return path, nil
+}
+
+func fetchGit(s source, dir string) error {
+	if err := git("", "clone", "--quiet", s.url, dir); err != nil {
+		return err
+	}
+	if s.ref == "" {
+		return nil
+	}
+	return git(dir, "checkout", "--quiet", s.ref)
+}
+
+func git(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("task: git %s failed: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (f *Fetcher) fetchHTTP(s source, dir string) error {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(s.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf(`task: "%s" returned "%s"`, s.url, resp.Status)
+	}
+
+	file, err := os.Create(filepath.Join(dir, "Taskfile.yml"))
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func cacheKey(rawurl string) string {
+	sum := sha256.Sum256([]byte(rawurl))
+	return hex.EncodeToString(sum[:])
+}
+
+func checksum(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
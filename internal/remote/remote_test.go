@@ -0,0 +1,126 @@
+package remote_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/leiyangyou/task/v2/internal/remote"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsRemote(t *testing.T) {
+	assert.True(t, remote.IsRemote("https://example.com/Taskfile.yml"))
+	assert.True(t, remote.IsRemote("git+https://example.com/repo.git#v1"))
+	assert.True(t, remote.IsRemote("git+file:///tmp/repo"))
+	assert.False(t, remote.IsRemote("./included"))
+}
+
+func TestFetchHTTP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "task-remote")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	content := "version: '2'\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/Taskfile.yml" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, content)
+	}))
+	defer server.Close()
+	url := server.URL + "/Taskfile.yml"
+
+	lockPath := filepath.Join(dir, remote.LockFile)
+	lock, err := remote.ReadLock(lockPath)
+	assert.NoError(t, err)
+	f := &remote.Fetcher{Dir: filepath.Join(dir, "cache"), Lock: lock}
+
+	path, err := f.Fetch(url)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, content, string(data))
+	assert.NoError(t, lock.Save())
+
+	// the cached copy is used while it matches the lock
+	content = "version: '2'\ntasks: {}\n"
+	lock, err = remote.ReadLock(lockPath)
+	assert.NoError(t, err)
+	f.Lock = lock
+	path, err = f.Fetch(url)
+	assert.NoError(t, err)
+	data, err = ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "version: '2'\n", string(data))
+
+	// a changed Taskfile doesn't match the lock
+	assert.NoError(t, os.RemoveAll(f.Dir))
+	_, err = f.Fetch(url)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "doesn't match")
+
+	f.Offline = true
+	_, err = f.Fetch(url)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "offline")
+
+	f.Offline = false
+	_, err = f.Fetch(server.URL + "/missing.yml")
+	assert.Error(t, err)
+}
+
+func TestFetchGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "task-remote")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	repo := filepath.Join(dir, "repo")
+	assert.NoError(t, os.MkdirAll(filepath.Join(repo, "ci"), 0755))
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=task", "-c", "user.email=task@example.com"}, args...)...)
+		cmd.Dir = repo
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+	writeTaskfile := func(content string) {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(repo, "ci", "Taskfile.yml"), []byte(content), 0644))
+	}
+
+	git("init", "--quiet")
+	writeTaskfile("version: '2'\n# v1\n")
+	git("add", "-A")
+	git("commit", "--quiet", "-m", "v1")
+	git("tag", "v1")
+	writeTaskfile("version: '2'\n# v2\n")
+	git("commit", "--quiet", "-am", "v2")
+
+	f := &remote.Fetcher{Dir: filepath.Join(dir, "cache")}
+	url := "git+file://" + filepath.ToSlash(repo)
+
+	path, err := f.Fetch(url + "//ci#v1")
+	assert.NoError(t, err)
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "version: '2'\n# v1\n", string(data))
+
+	path, err = f.Fetch(url + "//ci/Taskfile.yml")
+	assert.NoError(t, err)
+	data, err = ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "version: '2'\n# v2\n", string(data))
+
+	_, err = f.Fetch(url + "//ci#missing")
+	assert.Error(t, err)
+}
@@ -86,5 +86,9 @@ var checksumFilenameRegexp = regexp.MustCompile("[^A-z0-9]")
 
 // replaces invalid caracters on filenames with "-"
 func (*Checksum) normalizeFilename(f string) string {
+	return normalizeFilename(f)
+}
+
+func normalizeFilename(f string) string {
 	return checksumFilenameRegexp.ReplaceAllString(f, "-")
 }
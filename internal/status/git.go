@@ -0,0 +1,240 @@
+package status
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Git checks if a task is up to date by asking git which files changed
+// since the commit recorded on the last run, instead of hashing all the
+// source files. Only the sources modified or untracked on the working tree
+// are hashed, and the hash is recorded with the commit, so a run with the
+// same changes is up to date. It uses the git binary when available, and
+// falls back to reading the repository index otherwise.
+type Git struct {
+	Dir     string
+	Task    string
+	Sources []string
+	Dry     bool
+}
+
+// gitBackend abstracts how the repository state is queried
+type gitBackend interface {
+	// Head returns the commit HEAD points to, or an empty string if the
+	// repository has no commits yet
+	Head() (string, error)
+	// Committed returns true if any file accepted by match changed between
+	// the given commit and head
+	Committed(since, head string, match func(path string) bool) (bool, error)
+	// Dirty returns the absolute paths of the files accepted by match that
+	// are modified, removed or untracked on the working tree
+	Dirty(head string, match func(path string) bool) ([]string, error)
+}
+
+// ErrNotGitRepository is returned when the task directory is not inside
+// a git repository
+var ErrNotGitRepository = errors.New("task: method git requires the task to be inside a git repository")
+
+// useGitBinary can be disabled on tests to exercise the fallback
+var useGitBinary = true
+
+// IsUpToDate implements the Checker interface
+func (g *Git) IsUpToDate() (bool, error) {
+	dir, err := filepath.Abs(g.Dir)
+	if err != nil {
+		return false, err
+	}
+	// git reports paths with symlinks resolved
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	backend, err := newGitBackend(dir, g.Sources)
+	if err != nil {
+		return false, err
+	}
+
+	head, err := backend.Head()
+	if err != nil {
+		return false, err
+	}
+	match, err := Matcher(dir, g.Sources)
+	if err != nil {
+		return false, err
+	}
+	dirty, err := backend.Dirty(head, match)
+	if err != nil {
+		return false, err
+	}
+	dirtyHash, err := hashFiles(dirty)
+	if err != nil {
+		return false, err
+	}
+
+	// the state is the recorded commit, followed by the hash of the dirty
+	// files, if any
+	stateFile := g.stateFilePath()
+	data, _ := ioutil.ReadFile(stateFile)
+	recorded := strings.Fields(string(data))
+
+	upToDate := false
+	if len(recorded) > 0 {
+		committed, err := backend.Committed(recorded[0], head, match)
+		if err != nil {
+			return false, err
+		}
+		recordedHash := ""
+		if len(recorded) > 1 {
+			recordedHash = recorded[1]
+		}
+		upToDate = !committed && dirtyHash == recordedHash
+	}
+
+	if !g.Dry && head != "" {
+		state := head + "\n"
+		if dirtyHash != "" {
+			state += dirtyHash + "\n"
+		}
+		_ = os.MkdirAll(filepath.Dir(stateFile), 0755)
+		if err = ioutil.WriteFile(stateFile, []byte(state), 0644); err != nil {
+			return false, err
+		}
+	}
+	return upToDate, nil
+}
+
+// hashFiles hashes the paths and contents of files, or returns an empty
+// string if there are none. Missing files are hashed as removed
+func hashFiles(files []string) (string, error) {
+	if len(files) == 0 {
+		return "", nil
+	}
+	files = append([]string{}, files...)
+	sort.Strings(files)
+
+	h := sha1.New()
+	for _, f := range files {
+		fmt.Fprintf(h, "%s\x00", f)
+		file, err := os.Open(f)
+		if os.IsNotExist(err) {
+			fmt.Fprint(h, "removed\x00")
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, file)
+		file.Close()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprint(h, "\x00")
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// OnError implements the Checker interface
+func (g *Git) OnError() error {
+	return os.Remove(g.stateFilePath())
+}
+
+func (g *Git) stateFilePath() string {
+	return filepath.Join(g.Dir, ".task", "git", normalizeFilename(g.Task))
+}
+
+func newGitBackend(dir string, sources []string) (gitBackend, error) {
+	if useGitBinary {
+		if _, err := exec.LookPath("git"); err == nil {
+			root, err := runGit(dir, "rev-parse", "--show-toplevel")
+			if err != nil {
+				return nil, ErrNotGitRepository
+			}
+			return &gitBinary{root: strings.TrimSpace(root)}, nil
+		}
+	}
+
+	root, gitDir, err := findGitDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &gitIndex{root: root, gitDir: gitDir, dir: dir, sources: sources}, nil
+}
+
+// gitBinary queries the repository using the local git executable
+type gitBinary struct {
+	root string
+}
+
+func (g *gitBinary) Head() (string, error) {
+	head, err := runGit(g.root, "rev-parse", "--verify", "--quiet", "HEAD")
+	if err != nil {
+		// no commits yet
+		return "", nil
+	}
+	return strings.TrimSpace(head), nil
+}
+
+func (g *gitBinary) Committed(since, head string, match func(string) bool) (bool, error) {
+	if since == head {
+		return false, nil
+	}
+	diff, err := runGit(g.root, "diff", "--name-only", "-z", since, head, "--")
+	if err != nil {
+		// the recorded commit is gone (e.g. after a rebase)
+		return true, nil
+	}
+	return len(g.matching(diff, match)) > 0, nil
+}
+
+func (g *gitBinary) Dirty(head string, match func(string) bool) ([]string, error) {
+	// compares HEAD with the working tree, so it covers both staged and
+	// unstaged modifications. Without commits, all tracked files are new
+	args := []string{"diff", "--name-only", "-z", head, "--"}
+	if head == "" {
+		args = []string{"ls-files", "-z"}
+	}
+	diff, err := runGit(g.root, args...)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := runGit(g.root, "ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+	return append(g.matching(diff, match), g.matching(untracked, match)...), nil
+}
+
+// matching returns the absolute paths of the NUL separated paths of out
+// accepted by match
+func (g *gitBinary) matching(out string, match func(string) bool) []string {
+	var files []string
+	for _, f := range strings.Split(out, "\x00") {
+		if f == "" {
+			continue
+		}
+		if path := filepath.Join(g.root, filepath.FromSlash(f)); match(path) {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return stdout.String(), nil
+}
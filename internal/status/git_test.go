@@ -0,0 +1,137 @@
+package status
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupGitRepo(t *testing.T, indexVersion string) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+
+	dir, err := ioutil.TempDir("", "task-git")
+	assert.NoError(t, err)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=task", "GIT_AUTHOR_EMAIL=task@example.com",
+			"GIT_COMMITTER_NAME=task", "GIT_COMMITTER_EMAIL=task@example.com",
+		)
+		out, err := cmd.CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+
+	git("init", "--quiet")
+	writeFile(t, dir, ".gitignore", ".task/\nignored.txt\n")
+	writeFile(t, dir, "src/a.txt", "a")
+	writeFile(t, dir, "other.txt", "other")
+	git("add", ".")
+	git("update-index", "--index-version", indexVersion)
+	git("commit", "--quiet", "-m", "initial")
+
+	return dir
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+}
+
+func TestGit(t *testing.T) {
+	tests := []struct {
+		name         string
+		binary       bool
+		indexVersion string
+	}{
+		{"binary", true, "2"},
+		{"index v2", false, "2"},
+		{"index v3", false, "3"},
+		{"index v4", false, "4"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useGitBinary = test.binary
+			defer func() { useGitBinary = true }()
+
+			dir := setupGitRepo(t, test.indexVersion)
+			defer os.RemoveAll(dir)
+
+			g := &Git{Dir: dir, Task: "build", Sources: []string{"src/**/*.txt"}}
+			isUpToDate := func() bool {
+				upToDate, err := g.IsUpToDate()
+				assert.NoError(t, err)
+				return upToDate
+			}
+
+			assert.False(t, isUpToDate(), "first run should not be up to date")
+			assert.True(t, isUpToDate())
+
+			writeFile(t, dir, "other.txt", "changed")
+			assert.True(t, isUpToDate(), "files outside sources should be ignored")
+
+			writeFile(t, dir, "src/a.txt", "changed")
+			assert.False(t, isUpToDate(), "modified source")
+			assert.True(t, isUpToDate(), "same modified source as the last run")
+			writeFile(t, dir, "src/a.txt", "changed again")
+			assert.False(t, isUpToDate(), "modified source changed since the last run")
+			writeFile(t, dir, "src/a.txt", "a")
+			assert.False(t, isUpToDate(), "modification reverted since the last run")
+			assert.True(t, isUpToDate())
+
+			writeFile(t, dir, "src/b.txt", "b")
+			assert.False(t, isUpToDate(), "untracked source")
+			assert.True(t, isUpToDate(), "same untracked source as the last run")
+			assert.NoError(t, os.Remove(filepath.Join(dir, "src/b.txt")))
+			assert.False(t, isUpToDate(), "untracked source removed since the last run")
+			assert.True(t, isUpToDate())
+
+			assert.NoError(t, g.OnError())
+			assert.False(t, isUpToDate(), "state should be removed on error")
+		})
+	}
+}
+
+func TestGitCommitSinceLastRun(t *testing.T) {
+	dir := setupGitRepo(t, "2")
+	defer os.RemoveAll(dir)
+
+	g := &Git{Dir: dir, Task: "build", Sources: []string{"src/*.txt"}}
+	_, err := g.IsUpToDate()
+	assert.NoError(t, err)
+
+	writeFile(t, dir, "src/a.txt", "changed")
+	cmd := exec.Command("git", "-c", "user.name=task", "-c", "user.email=task@example.com", "commit", "--quiet", "-am", "change")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+
+	upToDate, err := g.IsUpToDate()
+	assert.NoError(t, err)
+	assert.False(t, upToDate, "committed change since last run")
+
+	upToDate, err = g.IsUpToDate()
+	assert.NoError(t, err)
+	assert.True(t, upToDate)
+}
+
+func TestGitNotRepository(t *testing.T) {
+	dir, err := ioutil.TempDir("", "task-git")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	useGitBinary = false
+	defer func() { useGitBinary = true }()
+
+	_, err = (&Git{Dir: dir, Task: "build", Sources: []string{"*"}}).IsUpToDate()
+	assert.Equal(t, ErrNotGitRepository, err)
+}
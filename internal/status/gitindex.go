@@ -0,0 +1,312 @@
+package status
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gitIndex queries the repository by reading the .git directory directly.
+// It is used when the git binary is not available, and is more
+// conservative: any new commit since the recorded one is considered a
+// change, and files ignored by .gitignore count as untracked.
+type gitIndex struct {
+	root    string
+	gitDir  string
+	dir     string
+	sources []string
+}
+
+type gitIndexEntry struct {
+	mtimeSec  uint32
+	mtimeNsec uint32
+	mode      uint32
+	size      uint32
+	sha       [sha1.Size]byte
+}
+
+func findGitDir(dir string) (root string, gitDir string, err error) {
+	for {
+		p := filepath.Join(dir, ".git")
+		info, err := os.Stat(p)
+		if err == nil {
+			if info.IsDir() {
+				return dir, p, nil
+			}
+			// worktrees and submodules have a file pointing to the git dir
+			data, err := ioutil.ReadFile(p)
+			if err != nil {
+				return "", "", err
+			}
+			gitDir := strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+			if !filepath.IsAbs(gitDir) {
+				gitDir = filepath.Join(dir, gitDir)
+			}
+			return dir, gitDir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", ErrNotGitRepository
+		}
+		dir = parent
+	}
+}
+
+func (g *gitIndex) Head() (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(g.gitDir, "HEAD"))
+	if err != nil {
+		return "", err
+	}
+	head := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(head, "ref:") {
+		return head, nil
+	}
+	return g.resolveRef(strings.TrimSpace(strings.TrimPrefix(head, "ref:")))
+}
+
+func (g *gitIndex) resolveRef(ref string) (string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(g.gitDir, filepath.FromSlash(ref)))
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	packed, err := ioutil.ReadFile(filepath.Join(g.gitDir, "packed-refs"))
+	if err != nil {
+		// no commits yet
+		return "", nil
+	}
+	for _, line := range strings.Split(string(packed), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == ref {
+			return fields[0], nil
+		}
+	}
+	return "", nil
+}
+
+func (g *gitIndex) Committed(since, head string, match func(string) bool) (bool, error) {
+	// reading commit trees is out of scope here, so any new commit is
+	// considered a change
+	return head != since, nil
+}
+
+// Dirty compares the working tree with the index, so staged modifications
+// are not found
+func (g *gitIndex) Dirty(head string, match func(string) bool) ([]string, error) {
+	entries, err := readGitIndex(filepath.Join(g.gitDir, "index"))
+	if err != nil {
+		return nil, err
+	}
+
+	var dirty []string
+	for path, entry := range entries {
+		path = filepath.Join(g.root, filepath.FromSlash(path))
+		if !match(path) {
+			continue
+		}
+		modified, err := entry.isModified(path)
+		if err != nil {
+			return nil, err
+		}
+		if modified {
+			dirty = append(dirty, path)
+		}
+	}
+
+	files, err := Glob(g.dir, g.sources)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		rel, err := filepath.Rel(g.root, f)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := entries[filepath.ToSlash(rel)]; ok {
+			continue
+		}
+		if info, err := os.Stat(f); err == nil && !info.IsDir() {
+			dirty = append(dirty, f)
+		}
+	}
+	return dirty, nil
+}
+
+// isModified does what git does: trusts the stat information when it
+// matches, and compares the blob hash otherwise
+func (entry *gitIndexEntry) isModified(path string) (bool, error) {
+	// submodules are not checked
+	if entry.mode&0170000 == 0160000 {
+		return false, nil
+	}
+
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if uint32(info.Size()) != entry.size {
+		return true, nil
+	}
+	mtime := info.ModTime()
+	if uint32(mtime.Unix()) == entry.mtimeSec && uint32(mtime.Nanosecond()) == entry.mtimeNsec {
+		return false, nil
+	}
+
+	sha, err := gitBlobHash(path, info)
+	if err != nil {
+		return false, err
+	}
+	return sha != entry.sha, nil
+}
+
+func gitBlobHash(path string, info os.FileInfo) (sum [sha1.Size]byte, err error) {
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", info.Size())
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return sum, err
+		}
+		io.WriteString(h, target)
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return sum, err
+		}
+		defer f.Close()
+		if _, err = io.Copy(h, f); err != nil {
+			return sum, err
+		}
+	}
+
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+var errInvalidGitIndex = errors.New("task: invalid git index")
+
+// readGitIndex parses a git index file (versions 2 to 4) returning its
+// entries by slash separated path relative to the repository root
+func readGitIndex(file string) (map[string]*gitIndexEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]*gitIndexEntry{}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	var header struct {
+		Signature [4]byte
+		Version   uint32
+		Count     uint32
+	}
+	if err = binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+	if string(header.Signature[:]) != "DIRC" || header.Version < 2 || header.Version > 4 {
+		return nil, errInvalidGitIndex
+	}
+
+	entries := make(map[string]*gitIndexEntry, header.Count)
+	var previousPath string
+
+	for i := uint32(0); i < header.Count; i++ {
+		var raw struct {
+			CtimeSec, CtimeNsec uint32
+			MtimeSec, MtimeNsec uint32
+			Dev, Ino            uint32
+			Mode                uint32
+			UID, GID            uint32
+			Size                uint32
+			Sha                 [sha1.Size]byte
+			Flags               uint16
+		}
+		if err = binary.Read(r, binary.BigEndian, &raw); err != nil {
+			return nil, err
+		}
+		entryLen := 62
+
+		if header.Version >= 3 && raw.Flags&0x4000 != 0 {
+			var extended uint16
+			if err = binary.Read(r, binary.BigEndian, &extended); err != nil {
+				return nil, err
+			}
+			entryLen += 2
+		}
+
+		var path string
+		if header.Version == 4 {
+			strip, err := readGitVarint(r)
+			if err != nil {
+				return nil, err
+			}
+			if strip > uint64(len(previousPath)) {
+				return nil, errInvalidGitIndex
+			}
+			suffix, err := r.ReadBytes(0)
+			if err != nil {
+				return nil, err
+			}
+			path = previousPath[:len(previousPath)-int(strip)] + string(suffix[:len(suffix)-1])
+		} else {
+			name, err := r.ReadBytes(0)
+			if err != nil {
+				return nil, err
+			}
+			path = string(name[:len(name)-1])
+			// entries are NUL padded to a multiple of eight bytes
+			padding := 8 - (entryLen+len(path))%8
+			if _, err = r.Discard(padding - 1); err != nil {
+				return nil, err
+			}
+		}
+		previousPath = path
+
+		// only stage 0 entries are interesting, conflicts are modifications
+		if stage := (raw.Flags >> 12) & 0x3; stage != 0 {
+			continue
+		}
+
+		entries[path] = &gitIndexEntry{
+			mtimeSec:  raw.MtimeSec,
+			mtimeNsec: raw.MtimeNsec,
+			mode:      raw.Mode,
+			size:      raw.Size,
+			sha:       raw.Sha,
+		}
+	}
+
+	return entries, nil
+}
+
+// readGitVarint reads the offset encoding used by git for index v4
+func readGitVarint(r io.ByteReader) (uint64, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	val := uint64(c & 127)
+	for c&128 != 0 {
+		if c, err = r.ReadByte(); err != nil {
+			return 0, err
+		}
+		val = ((val + 1) << 7) | uint64(c&127)
+	}
+	return val, nil
+}
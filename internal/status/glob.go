@@ -80,3 +80,34 @@ func Glob(dir string, globs []string) ([]string, error) {
 
 	return files, nil
 }
+
+// Matcher returns a func that reports whether an absolute path is matched
+// by the given globs, like Glob would, but without touching the file system
+func Matcher(dir string, globs []string) (func(string) bool, error) {
+	var included, excluded []string
+
+	err := VisitGlobs(dir, globs, func(glob string, exclude bool) error {
+		if exclude {
+			excluded = append(excluded, glob)
+		} else {
+			included = append(included, glob)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	matchAny := func(patterns []string, path string) bool {
+		for _, p := range patterns {
+			if ok, _ := doublestar.PathMatch(p, path); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(path string) bool {
+		return matchAny(included, path) && !matchAny(excluded, path)
+	}, nil
+}
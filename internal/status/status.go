@@ -3,6 +3,7 @@ package status
 var (
 	_ Checker = &Timestamp{}
 	_ Checker = &Checksum{}
+	_ Checker = &Git{}
 	_ Checker = None{}
 )
 
@@ -11,3 +12,12 @@ type Checker interface {
 	IsUpToDate() (bool, error)
 	OnError() error
 }
+
+// StatePaths returns the files where checkers keep the state of a task
+// between runs
+func StatePaths(dir, task string) []string {
+	return []string{
+		(&Checksum{Dir: dir, Task: task}).checksumFilePath(),
+		(&Git{Dir: dir, Task: task}).stateFilePath(),
+	}
+}
@@ -0,0 +1,77 @@
+package taskfile
+
+import (
+	"errors"
+	"strings"
+)
+
+// Include is an included Taskfile
+type Include struct {
+	// Taskfile is the path of the Taskfile, or of its directory, relative
+	// to the including Taskfile
+	Taskfile string
+	// Dir is where the included tasks run, relative to the including
+	// Taskfile. Defaults to the directory of the included Taskfile
+	Dir string
+	// Optional includes are skipped when the Taskfile doesn't exist
+	Optional bool
+	// Vars are available to the included Taskfile
+	Vars Vars
+	// Aliases are other namespaces the included tasks can be called with
+	Aliases []string
+
+	// ShortSyntax is set when the include is given only by its path. The
+	// included tasks then run on the directory of the including Taskfile,
+	// unless Dir is set
+	ShortSyntax bool
+}
+
+// ErrCantUnmarshalInclude is returned for invalid include YAML
+var ErrCantUnmarshalInclude = errors.New("task: can't unmarshal include value")
+
+// UnmarshalYAML implements yaml.Unmarshaler interface
+func (i *Include) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var path string
+	if err := unmarshal(&path); err == nil {
+		i.Taskfile = path
+		i.ShortSyntax = true
+		return nil
+	}
+	var include struct {
+		Taskfile string
+		Dir      string
+		Optional bool
+		Vars     Vars
+		Aliases  []string
+	}
+	if err := unmarshal(&include); err == nil && include.Taskfile != "" {
+		i.Taskfile = include.Taskfile
+		i.Dir = include.Dir
+		i.Optional = include.Optional
+		i.Vars = include.Vars
+		i.Aliases = include.Aliases
+		return nil
+	}
+	return ErrCantUnmarshalInclude
+}
+
+// ResolveAlias returns the name of a task called through a namespace
+// alias, or the name itself if it doesn't start with one
+func (tf *Taskfile) ResolveAlias(task string) string {
+	// aliases of nested includes can be chained, e.g. "s:d:task" can be
+	// "svc:d:task" and then "svc:db:task"
+	for i := 0; i <= len(tf.Aliases); i++ {
+		resolved := task
+		for alias, namespace := range tf.Aliases {
+			if strings.HasPrefix(task, alias+":") {
+				resolved = namespace + strings.TrimPrefix(task, alias)
+				break
+			}
+		}
+		if resolved == task {
+			return task
+		}
+		task = resolved
+	}
+	return task
+}
@@ -2,14 +2,42 @@ package taskfile
 
 import (
 	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
 )
 
-// Merge merges the second Taskfile into the first
+// MergeReport describes what merging a Taskfile into another changed
+type MergeReport struct {
+	// File is the file merged, and Into the one it was merged into
+	File string
+	Into string
+	// Tasks are the tasks added, and OverriddenTasks and OverriddenEnv the
+	// ones replaced
+	Tasks           []string
+	OverriddenTasks []string
+	OverriddenEnv   []string
+}
+
+// Merge merges the second Taskfile into the first. Their major versions
+// should match, and the version of the first is kept.
+//
+// The vars of the second Taskfile are not merged: they stay scoped to its
+// tasks, as their TaskfileVars. So do its dotenv files, which are added to
+// the ones of its tasks. Its env is merged, and its tasks and includes
+// replace the ones with the same name
 func Merge(t1, t2 *Taskfile) error {
-	if t1.Version != t2.Version {
-		return fmt.Errorf(`Taskfiles versions should match. First is "%s" but second is "%s"`, t1.Version, t2.Version)
+	if majorVersion(t1.Version) != majorVersion(t2.Version) {
+		return fmt.Errorf(`Taskfiles major versions should match. First is "%s" but second is "%s"`, t1.Version, t2.Version)
 	}
 
+	var report MergeReport
+	if len(t2.Files) > 0 {
+		report.File = t2.Files[0]
+	}
+	if len(t1.Files) > 0 {
+		report.Into = t1.Files[0]
+	}
 	if t2.Expansions != 0 && t2.Expansions != 2 {
 		t1.Expansions = t2.Expansions
 	}
@@ -18,6 +46,51 @@ func Merge(t1, t2 *Taskfile) error {
 		t1.Output = t2.Output
 	}
 
+	t1.Files = append(t1.Files, t2.Files...)
+	t1.Duplicates = append(t1.Duplicates, t2.Duplicates...)
+
+	for namespace, include := range t2.Includes {
+		if t1.Includes == nil {
+			t1.Includes = make(map[string]*Include)
+		}
+		t1.Includes[namespace] = include
+	}
+
+	for name, profile := range t2.Profiles {
+		if t1.Profiles == nil {
+			t1.Profiles = make(map[string]*Profile)
+		}
+		t1.Profiles[name] = profile
+	}
+
+	for alias, namespace := range t2.Aliases {
+		if t1.Aliases == nil {
+			t1.Aliases = make(map[string]string)
+		}
+		t1.Aliases[alias] = namespace
+	}
+
+	ignore, err := rebaseIgnore(t1, t2)
+	if err != nil {
+		return err
+	}
+	t1.Watch.Ignore = append(t1.Watch.Ignore, ignore...)
+	if t2.Watch.Debounce != 0 {
+		t1.Watch.Debounce = t2.Watch.Debounce
+	}
+	if t2.Watch.Signal != "" {
+		t1.Watch.Signal = t2.Watch.Signal
+	}
+	if t2.Watch.GracePeriod != 0 {
+		t1.Watch.GracePeriod = t2.Watch.GracePeriod
+	}
+	if t2.Watch.OnSuccess != "" {
+		t1.Watch.OnSuccess = t2.Watch.OnSuccess
+	}
+	if t2.Watch.OnFailure != "" {
+		t1.Watch.OnFailure = t2.Watch.OnFailure
+	}
+
 	if t1.Vars == nil {
 		t1.Vars = make(Vars)
 	}
@@ -26,6 +99,9 @@ func Merge(t1, t2 *Taskfile) error {
 		t1.Env = make(Vars)
 	}
 	for k, v := range t2.Env {
+		if _, ok := t1.Env[k]; ok {
+			report.OverriddenEnv = append(report.OverriddenEnv, k)
+		}
 		t1.Env[k] = v
 	}
 
@@ -33,10 +109,95 @@ func Merge(t1, t2 *Taskfile) error {
 		t1.Tasks = make(Tasks)
 	}
 
+	dotenv := t2.Dotenv
+	if len(dotenv) > 0 && len(t2.Files) > 0 {
+		// relative to the Taskfile, instead of to the directory of the tasks
+		dir, err := filepath.Abs(filepath.Dir(t2.Files[0]))
+		if err != nil {
+			return err
+		}
+		dotenv = make([]string, len(t2.Dotenv))
+		for i, path := range t2.Dotenv {
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(dir, path)
+			}
+			dotenv[i] = path
+		}
+	}
+
 	for k, v := range t2.Tasks {
+		if v != nil && v.TaskfileVars == nil {
+			v.TaskfileVars = t2.Vars
+		}
+		if v != nil && len(dotenv) > 0 {
+			v.Dotenv = append(append([]string{}, dotenv...), v.Dotenv...)
+		}
+		if _, ok := t1.Tasks[k]; ok {
+			report.OverriddenTasks = append(report.OverriddenTasks, k)
+		} else {
+			report.Tasks = append(report.Tasks, k)
+		}
 		t1.Tasks[k] = v
 	}
 
+	sort.Strings(report.Tasks)
+	sort.Strings(report.OverriddenTasks)
+	sort.Strings(report.OverriddenEnv)
+	t1.Merges = append(t1.Merges, t2.Merges...)
+	t1.Merges = append(t1.Merges, report)
+
 	return nil
 }
 
+// rebaseIgnore returns the watch ignore patterns of t2, which are relative
+// to its directory, relative to the directory of t1
+func rebaseIgnore(t1, t2 *Taskfile) ([]string, error) {
+	if len(t2.Watch.Ignore) == 0 || len(t1.Files) == 0 || len(t2.Files) == 0 {
+		return t2.Watch.Ignore, nil
+	}
+	dir1, err := filepath.Abs(filepath.Dir(t1.Files[0]))
+	if err != nil {
+		return nil, err
+	}
+	dir2, err := filepath.Abs(filepath.Dir(t2.Files[0]))
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(dir1, dir2)
+	if err != nil {
+		return nil, err
+	}
+	if rel == "." {
+		return t2.Watch.Ignore, nil
+	}
+	rel = filepath.ToSlash(rel)
+
+	patterns := make([]string, len(t2.Watch.Ignore))
+	for i, p := range t2.Watch.Ignore {
+		patterns[i] = rebaseIgnorePattern(rel, p)
+	}
+	return patterns, nil
+}
+
+// rebaseIgnorePattern prefixes a gitignore-style pattern with dir. Patterns
+// without a slash, which match on any level, are kept matching on any level
+// below dir
+func rebaseIgnorePattern(dir, p string) string {
+	if trimmed := strings.TrimSpace(p); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return p
+	}
+	var negate string
+	if strings.HasPrefix(p, "!") {
+		negate, p = "!", p[1:]
+	} else {
+		p = strings.TrimPrefix(p, `\`)
+	}
+	if !strings.Contains(strings.TrimSuffix(p, "/"), "/") {
+		p = "**/" + p
+	}
+	return negate + dir + "/" + strings.TrimPrefix(p, "/")
+}
+
+func majorVersion(version string) string {
+	return strings.SplitN(version, ".", 2)[0]
+}
@@ -0,0 +1,32 @@
+package taskfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DefaultNames are the names a Taskfile is looked for with on a directory,
+// by order of precedence. Taskfile.dist.yml is meant to be committed, and
+// overridden by an untracked Taskfile.yml, which is merged over it
+var DefaultNames = []string{
+	"Taskfile.yml",
+	"taskfile.yml",
+	"Taskfile.yaml",
+	"taskfile.yaml",
+	"Taskfile.dist.yml",
+	"taskfile.dist.yml",
+	"Taskfile.dist.yaml",
+	"taskfile.dist.yaml",
+}
+
+// FindInDir returns the path of the Taskfile on a directory, and whether
+// there's one
+func FindInDir(dir string) (string, bool) {
+	for _, name := range DefaultNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,13 @@
+package taskfile
+
+// Profile is a set of overrides selected with --profile, like an
+// environment the tasks run for
+type Profile struct {
+	// Vars take precedence over the vars of the Taskfiles, but not over
+	// the ones given when calling a task
+	Vars Vars
+	// Env overrides the env of the Taskfile
+	Env Vars
+	// Includes are included only when the profile is selected
+	Includes map[string]*Include
+}
@@ -0,0 +1,163 @@
+package read
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/leiyangyou/task/v2/internal/taskfile"
+)
+
+// Dotenv reads a dotenv file. Its lines are KEY=VALUE pairs, optionally
+// prefixed by "export", and comments starting with "#". Values can be
+// single quoted, taken literally, or double quoted, where \n, \r, \t, \",
+// \$ and \\ are escapes, and both can span multiple lines. Unquoted values
+// end on a " #" comment
+func Dotenv(path string) (taskfile.Vars, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := parseDotenv(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("unable to deserialize file %v due to: %v", path, err)
+	}
+	return vars, nil
+}
+
+// DotenvFiles reads the given dotenv files, relative to dir, with the later
+// ones overriding the earlier ones. Missing files are skipped. The paths are
+// returned, whether they exist or not
+func DotenvFiles(dir string, paths []string) (taskfile.Vars, []string, error) {
+	vars := make(taskfile.Vars)
+	resolved := make([]string, len(paths))
+	for i, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		resolved[i] = path
+
+		fileVars, err := Dotenv(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+	return vars, resolved, nil
+}
+
+func parseDotenv(s string) (taskfile.Vars, error) {
+	vars := make(taskfile.Vars)
+	lines := strings.Split(s, "\n")
+	for n := 0; n < len(lines); n++ {
+		lineNumber := n + 1
+		line := strings.TrimLeft(strings.TrimSuffix(lines[n], "\r"), " \t")
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if strings.HasPrefix(line, "export ") {
+			line = strings.TrimLeft(strings.TrimPrefix(line, "export "), " \t")
+		}
+
+		i := strings.IndexByte(line, '=')
+		if i == -1 {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+		key := strings.TrimSpace(line[:i])
+		if !validDotenvKey(key) {
+			return nil, fmt.Errorf(`line %d: invalid name "%s"`, lineNumber, key)
+		}
+		value := strings.TrimLeft(line[i+1:], " \t")
+
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			if i := strings.Index(value, " #"); i != -1 {
+				value = value[:i]
+			}
+			if i := strings.Index(value, "\t#"); i != -1 {
+				value = value[:i]
+			}
+			vars[key] = taskfile.Var{Static: strings.TrimSpace(value)}
+			continue
+		}
+
+		// quoted values continue on the next lines until the closing quote
+		quote := value[0]
+		value = value[1:]
+		end := closingQuote(value, quote)
+		for end == -1 {
+			n++
+			if n == len(lines) {
+				return nil, fmt.Errorf("line %d: unterminated quoted value", lineNumber)
+			}
+			value += "\n" + strings.TrimSuffix(lines[n], "\r")
+			end = closingQuote(value, quote)
+		}
+		if rest := strings.TrimSpace(value[end+1:]); rest != "" && rest[0] != '#' {
+			return nil, fmt.Errorf("line %d: unexpected %q after the quoted value", lineNumber, rest)
+		}
+		value = value[:end]
+		if quote == '"' {
+			value = unescapeDotenv(value)
+		}
+		vars[key] = taskfile.Var{Static: value}
+	}
+	return vars, nil
+}
+
+// closingQuote returns the index of the quote closing s, or -1. Double
+// quotes can be escaped
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeDotenv(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '$':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func validDotenvKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if r != '_' && r != '.' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package read_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/leiyangyou/task/v2/internal/taskfile"
+	"github.com/leiyangyou/task/v2/internal/taskfile/read"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDotenv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "task-dotenv")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		content  string
+		expected taskfile.Vars
+		err      bool
+	}{
+		{
+			content: "# comment\n\nFOO=bar\r\nexport BAZ = qux # comment\nEMPTY=\nHASH=a#b\n",
+			expected: taskfile.Vars{
+				"FOO":   taskfile.Var{Static: "bar"},
+				"BAZ":   taskfile.Var{Static: "qux"},
+				"EMPTY": taskfile.Var{Static: ""},
+				"HASH":  taskfile.Var{Static: "a#b"},
+			},
+		},
+		{
+			content: `SINGLE='a \n "b" # c'` + "\n" + `DOUBLE="a\n\t\"b\" \$c \\ # d" # comment` + "\n",
+			expected: taskfile.Vars{
+				"SINGLE": taskfile.Var{Static: `a \n "b" # c`},
+				"DOUBLE": taskfile.Var{Static: "a\n\t\"b\" $c \\ # d"},
+			},
+		},
+		{
+			content: "MULTI=\"first\nsecond\"\nNEXT='x\ny'\n",
+			expected: taskfile.Vars{
+				"MULTI": taskfile.Var{Static: "first\nsecond"},
+				"NEXT":  taskfile.Var{Static: "x\ny"},
+			},
+		},
+		{content: "FOO\n", err: true},
+		{content: "FOO BAR=baz\n", err: true},
+		{content: "FOO=\"unterminated\n", err: true},
+		{content: "FOO='a' b\n", err: true},
+	}
+	for i, test := range tests {
+		path := filepath.Join(dir, ".env")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(test.content), 0644))
+		vars, err := read.Dotenv(path)
+		if test.err {
+			assert.Error(t, err, "test %d", i)
+			continue
+		}
+		assert.NoError(t, err, "test %d", i)
+		assert.Equal(t, test.expected, vars, "test %d", i)
+	}
+}
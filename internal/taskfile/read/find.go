@@ -0,0 +1,30 @@
+package read
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/leiyangyou/task/v2/internal/taskfile"
+)
+
+// FindTaskfile returns the path of the Taskfile on dir, or on the nearest
+// of its parent directories having one
+func FindTaskfile(dir string) (string, error) {
+	if path, ok := taskfile.FindInDir(dir); ok {
+		return path, nil
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for d := filepath.Dir(abs); ; d = filepath.Dir(d) {
+		if path, ok := taskfile.FindInDir(d); ok {
+			return path, nil
+		}
+		if d == filepath.Dir(d) {
+			break
+		}
+	}
+	return "", fmt.Errorf(`task: No Taskfile found on "%s" or any of its parent directories, use "task --init" to create a new one`, dir)
+}
@@ -0,0 +1,127 @@
+package read
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/leiyangyou/task/v2/internal/remote"
+	"github.com/leiyangyou/task/v2/internal/taskfile"
+
+	"github.com/bmatcuk/doublestar"
+)
+
+// resolvedInclude is an include resolved to the path of a Taskfile
+type resolvedInclude struct {
+	// namespaces are added to the ones of the including Taskfile
+	namespaces []string
+	path       string
+	include    *taskfile.Include
+	// remote is set for includes fetched to the cache
+	remote bool
+}
+
+// resolveIncludes returns the Taskfiles included on the given directory,
+// sorted by namespace. An include whose taskfile is a glob includes all the
+// matches, each on the namespace of the name of its directory. Remote
+// includes are fetched
+func (r *reader) resolveIncludes(dir string, includes map[string]*taskfile.Include) ([]resolvedInclude, error) {
+	var result []resolvedInclude
+	for namespace, include := range includes {
+		var namespaces []string
+		if !strings.HasPrefix(namespace, ".") {
+			namespaces = []string{namespace}
+		}
+
+		if remote.IsRemote(include.Taskfile) {
+			path, err := r.fetch(include.Taskfile)
+			if err != nil {
+				if include.Optional {
+					continue
+				}
+				return nil, err
+			}
+			result = append(result, resolvedInclude{namespaces: namespaces, path: path, include: include, remote: true})
+			continue
+		}
+
+		pattern := filepath.Join(dir, include.Taskfile)
+		if !isGlob(include.Taskfile) {
+			path := taskfilePath(pattern)
+			if _, err := os.Stat(path); err != nil {
+				if include.Optional {
+					continue
+				}
+				return nil, err
+			}
+			result = append(result, resolvedInclude{namespaces: namespaces, path: path, include: include})
+			continue
+		}
+
+		if len(include.Aliases) > 0 {
+			return nil, fmt.Errorf(`task: Aliases can't be used on the glob include "%s"`, namespace)
+		}
+		matches, err := doublestar.Glob(pattern)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, match := range matches {
+			path := taskfilePath(match)
+			if path != match {
+				// directories without a Taskfile are not included
+				if _, err := os.Stat(path); err != nil {
+					continue
+				}
+			}
+			matchNamespaces := append(append([]string{}, namespaces...), filepath.Base(filepath.Dir(path)))
+			result = append(result, resolvedInclude{namespaces: matchNamespaces, path: path, include: include})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		ni := strings.Join(result[i].namespaces, NamespaceSeparator)
+		nj := strings.Join(result[j].namespaces, NamespaceSeparator)
+		if ni != nj {
+			return ni < nj
+		}
+		return result[i].path < result[j].path
+	})
+
+	paths := make(map[string]string, len(result))
+	for _, inc := range result {
+		if len(inc.namespaces) == 0 {
+			continue
+		}
+		namespace := strings.Join(inc.namespaces, NamespaceSeparator)
+		if other, ok := paths[namespace]; ok {
+			return nil, fmt.Errorf(`task: Namespace "%s" is included from both "%s" and "%s"`, namespace, other, inc.path)
+		}
+		paths[namespace] = inc.path
+	}
+	return result, nil
+}
+
+func (r *reader) fetch(url string) (string, error) {
+	if r.opts.Remote == nil {
+		return "", fmt.Errorf(`task: Unable to include "%s": remote includes are not enabled`, url)
+	}
+	return r.opts.Remote.Fetch(url)
+}
+
+// taskfilePath returns the path of the Taskfile of a directory, or the path
+// itself if it's not a directory
+func taskfilePath(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		if found, ok := taskfile.FindInDir(path); ok {
+			return found
+		}
+		return filepath.Join(path, taskfile.DefaultNames[0])
+	}
+	return path
+}
+
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
@@ -0,0 +1,30 @@
+package read
+
+import (
+	"fmt"
+
+	"github.com/leiyangyou/task/v2/internal/taskfile"
+)
+
+// addProfileIncludes adds the includes of a profile to the ones of the
+// Taskfile declaring it
+func addProfileIncludes(t *taskfile.Taskfile, name string) error {
+	profile, ok := t.Profiles[name]
+	if !ok {
+		return fmt.Errorf(`task: Profile "%s" is not defined`, name)
+	}
+	if profile == nil {
+		return nil
+	}
+
+	for namespace, include := range profile.Includes {
+		if _, ok := t.Includes[namespace]; ok {
+			return fmt.Errorf(`task: Namespace "%s" is included by both the Taskfile and the profile "%s"`, namespace, name)
+		}
+		if t.Includes == nil {
+			t.Includes = make(map[string]*taskfile.Include)
+		}
+		t.Includes[namespace] = include
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/leiyangyou/task/v2/internal/remote"
 	"github.com/leiyangyou/task/v2/internal/taskfile"
 
 	"gopkg.in/yaml.v2"
@@ -14,19 +15,77 @@ import (
 
 const NamespaceSeparator = ":"
 
+// Options configure how Taskfiles are read
+type Options struct {
+	// Remote fetches the remote includes. They fail if it's nil
+	Remote *remote.Fetcher
+	// Profile is the profile whose includes are added to the ones of the
+	// entry Taskfile. It must be defined on it, or on its local Taskfile
+	Profile string
+}
+
+type reader struct {
+	opts Options
+}
+
 // Taskfile reads a Taskfile for a given directory
 func Taskfile(path string, parentVars taskfile.Vars, namespaces ...string) (*taskfile.Taskfile, error) {
+	return TaskfileWithOptions(path, parentVars, Options{}, namespaces...)
+}
+
+// TaskfileWithOptions reads a Taskfile like Taskfile, with options
+func TaskfileWithOptions(path string, parentVars taskfile.Vars, opts Options, namespaces ...string) (*taskfile.Taskfile, error) {
+	r := &reader{opts: opts}
+	return r.taskfile(path, parentVars, nil, namespaces...)
+}
+
+// taskfile reads a Taskfile included through the Taskfiles of chain,
+// failing if it's one of them. The entry Taskfile, with an empty chain, is
+// merged over its Taskfile.dist.yml, if it's a Taskfile.yml, and is
+// overridden by its local Taskfile
+func (r *reader) taskfile(path string, parentVars taskfile.Vars, chain []string, namespaces ...string) (*taskfile.Taskfile, error) {
 	dir := filepath.Dir(path)
 
 	if _, err := os.Stat(path); err != nil {
 		return nil, fmt.Errorf(`task: task file %s is not found, use "task --init" to create a new one`, path)
 	}
+	entry := len(chain) == 0
+	var overrides []string
+	if entry {
+		if dist, ok := DistTaskfile(path); ok {
+			path, overrides = dist, []string{path}
+		}
+		if localPath := LocalTaskfile(path); fileExists(localPath) {
+			overrides = append(overrides, localPath)
+		}
+	}
+	chain, err := includeChain(chain, path)
+	if err != nil {
+		return nil, err
+	}
 	t, err := readTaskfile(path)
 	if err != nil {
 		return nil, err
 	}
+	for _, overridePath := range overrides {
+		// the vars and profiles of the overrides are merged before anything
+		// uses the ones of the Taskfile, so they override them for its tasks
+		// and includes
+		override, err := readTaskfile(overridePath)
+		if err != nil {
+			return nil, err
+		}
+		t.Vars = t.Vars.Merge(override.Vars)
+		for name, profile := range override.Profiles {
+			if t.Profiles == nil {
+				t.Profiles = make(map[string]*taskfile.Profile)
+			}
+			t.Profiles[name] = profile
+		}
+	}
 
 	t.Vars = parentVars.Merge(t.Vars)
+	t.Files = []string{path}
 
 	var taskNames []string
 
@@ -61,41 +120,64 @@ func Taskfile(path string, parentVars taskfile.Vars, namespaces ...string) (*tas
 		}
 
 		task.TaskfileVars = t.Vars
+		task.Location = path
 
 		task.Task = nameWithNamespace
 	}
 
-	for includedNamespace, includedPath := range t.Includes {
-		includedPath = filepath.Join(dir, includedPath)
-		info, err := os.Stat(includedPath)
-		if err != nil {
+	if entry && r.opts.Profile != "" {
+		if err = addProfileIncludes(t, r.opts.Profile); err != nil {
 			return nil, err
 		}
-		if info.IsDir() {
-			includedPath = filepath.Join(includedPath, "Taskfile.yml")
-		}
-
-		var includedNamespaces []string
-
-		if strings.HasPrefix(includedNamespace, ".") {
-			includedNamespaces = namespaces
-		} else {
-			includedNamespaces = append(namespaces, includedNamespace)
+	}
+	includes, err := r.resolveIncludes(dir, t.Includes)
+	if err != nil {
+		return nil, err
+	}
+	// like tasks, includes are kept by their full namespace once merged
+	if len(namespaces) > 0 {
+		namespacedIncludes := make(map[string]*taskfile.Include, len(t.Includes))
+		for namespace, include := range t.Includes {
+			namespacedIncludes[taskNameWithNamespace(namespace, namespaces...)] = include
 		}
+		t.Includes = namespacedIncludes
+	}
+	for _, inc := range includes {
+		include := inc.include
+		includedNamespaces := append(append([]string{}, namespaces...), inc.namespaces...)
 
-		includedTaskfile, err := Taskfile(includedPath, t.Vars, includedNamespaces...)
+		included, err := r.taskfile(inc.path, t.Vars.Merge(include.Vars), chain, includedNamespaces...)
 		if err != nil {
 			return nil, err
 		}
-		if err = taskfile.Merge(t, includedTaskfile); err != nil {
+		if err = setIncludedTasksDir(included, dir, inc, include); err != nil {
+			return nil, err
+		}
+		if len(includedNamespaces) > len(namespaces) {
+			for _, alias := range include.Aliases {
+				if t.Aliases == nil {
+					t.Aliases = make(map[string]string)
+				}
+				aliasNamespaces := append(append([]string{}, namespaces...), alias)
+				t.Aliases[strings.Join(aliasNamespaces, NamespaceSeparator)] = strings.Join(includedNamespaces, NamespaceSeparator)
+			}
+		}
+		for name, task := range included.Tasks {
+			if existing, ok := t.Tasks[name]; ok {
+				t.Duplicates = append(t.Duplicates, taskfile.DuplicateTask{
+					Task:  name,
+					Files: []string{existing.Location, task.Location},
+				})
+			}
+		}
+		if err = taskfile.Merge(t, included); err != nil {
 			return nil, err
 		}
 	}
 
-	baseTaskName := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
-	path = filepath.Join(dir, fmt.Sprintf("%s_%s.yml", baseTaskName, runtime.GOOS))
+	path = OSTaskfile(path)
 	if _, err = os.Stat(path); err == nil {
-		osTaskfile, err := Taskfile(path, t.Vars, namespaces...)
+		osTaskfile, err := r.taskfile(path, t.Vars, chain, namespaces...)
 		if err != nil {
 			return nil, err
 		}
@@ -103,9 +185,127 @@ func Taskfile(path string, parentVars taskfile.Vars, namespaces ...string) (*tas
 			return nil, err
 		}
 	}
+
+	for _, overridePath := range overrides {
+		override, err := r.taskfile(overridePath, t.Vars, chain, namespaces...)
+		if err != nil {
+			return nil, err
+		}
+		if err = taskfile.Merge(t, override); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, task := range t.Tasks {
+		for _, dep := range task.Deps {
+			dep.Task = t.ResolveAlias(dep.Task)
+		}
+		for _, cmd := range task.Cmds {
+			if cmd.Task != "" {
+				cmd.Task = t.ResolveAlias(cmd.Task)
+			}
+		}
+	}
 	return t, nil
 }
 
+// setIncludedTasksDir makes the included tasks run on the directory of the
+// include, and their relative dirs relative to it. Short syntax and remote
+// includes without a dir keep running on the directory of the including
+// Taskfile. A dir given on the include may have templates, so it's only
+// resolved when the tasks are compiled
+func setIncludedTasksDir(t *taskfile.Taskfile, dir string, inc resolvedInclude, include *taskfile.Include) error {
+	var tasksDir string
+	switch {
+	case include.Dir != "":
+		parent, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		for _, task := range t.Tasks {
+			// tasks of nested includes already have their dir
+			if !filepath.IsAbs(task.Dir) && task.IncludeDir == "" {
+				task.IncludeDir, task.IncludeParent = include.Dir, parent
+			}
+		}
+		return nil
+	case !include.ShortSyntax && !inc.remote:
+		tasksDir = filepath.Dir(inc.path)
+	default:
+		return nil
+	}
+	tasksDir, err := filepath.Abs(tasksDir)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range t.Tasks {
+		if !filepath.IsAbs(task.Dir) && task.IncludeDir == "" {
+			task.Dir = filepath.Join(tasksDir, task.Dir)
+		}
+	}
+	return nil
+}
+
+// LocalTaskfile returns the path of the untracked file that overrides the
+// given Taskfile, e.g. Taskfile.local.yml for Taskfile.yml or
+// Taskfile.dist.yml
+func LocalTaskfile(path string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ext), ".dist")
+	return filepath.Join(filepath.Dir(path), base+".local"+ext)
+}
+
+// DistTaskfile returns the path of the Taskfile.dist.yml the given Taskfile
+// is merged over, and whether there's one. Only Taskfiles with one of the
+// default names, other than the dist ones, are merged over it
+func DistTaskfile(path string) (string, bool) {
+	base := filepath.Base(path)
+	if strings.Contains(base, ".dist.") {
+		return "", false
+	}
+	isDefault := false
+	for _, name := range taskfile.DefaultNames {
+		isDefault = isDefault || name == base
+	}
+	if !isDefault {
+		return "", false
+	}
+	for _, name := range taskfile.DefaultNames {
+		if dist := filepath.Join(filepath.Dir(path), name); strings.Contains(name, ".dist.") && fileExists(dist) {
+			return dist, true
+		}
+	}
+	return "", false
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// includeChain appends path to chain, or fails if it's already on it
+func includeChain(chain []string, path string) ([]string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range chain {
+		if p == abs {
+			cycle := append(append([]string{}, chain[i:]...), abs)
+			return nil, fmt.Errorf("task: Include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	return append(append([]string{}, chain...), abs), nil
+}
+
+// OSTaskfile returns the path of the file that overrides the given Taskfile
+// on the current OS, e.g. Taskfile_linux.yml
+func OSTaskfile(path string) string {
+	baseTaskName := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return filepath.Join(filepath.Dir(path), fmt.Sprintf("%s_%s.yml", baseTaskName, runtime.GOOS))
+}
+
 func readTaskfile(file string) (*taskfile.Taskfile, error) {
 	f, err := os.Open(file)
 	if err != nil {
@@ -11,11 +11,23 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
+// TaskvarsFiles returns the paths Taskvars reads from, whether they exist
+// or not
+func TaskvarsFiles(dir string) []string {
+	return []string{
+		filepath.Join(dir, "Taskvars.yml"),
+		filepath.Join(dir, fmt.Sprintf("Taskvars_%s.yml", runtime.GOOS)),
+		filepath.Join(dir, "Taskvars.local.yml"),
+	}
+}
+
 // Taskvars reads a Taskvars for a given directory
 func Taskvars(dir string) (taskfile.Vars, error) {
 	vars := make(taskfile.Vars)
 
-	path := filepath.Join(dir, "Taskvars.yml")
+	files := TaskvarsFiles(dir)
+
+	path := files[0]
 	if _, err := os.Stat(path); err == nil {
 		vars, err = readTaskvars(path)
 		if err != nil {
@@ -23,17 +35,20 @@ func Taskvars(dir string) (taskfile.Vars, error) {
 		}
 	}
 
-	path = filepath.Join(dir, fmt.Sprintf("Taskvars_%s.yml", runtime.GOOS))
-	if _, err := os.Stat(path); err == nil {
-		osVars, err := readTaskvars(path)
+	// the OS specific and then the local vars override the ones before
+	for _, path = range files[1:] {
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		overrideVars, err := readTaskvars(path)
 		if err != nil {
 			return nil, err
 		}
 
 		if vars == nil {
-			vars = osVars
+			vars = overrideVars
 		} else {
-			for k, v := range osVars {
+			for k, v := range overrideVars {
 				vars[k] = v
 			}
 		}
@@ -0,0 +1,21 @@
+package read
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// UserDir returns the directory of the user Taskfile: $XDG_CONFIG_HOME/task
+// if set, ~/.config/task otherwise
+func UserDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "task"), nil
+	}
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "task"), nil
+}
@@ -18,8 +18,21 @@ type Task struct {
 	Dir          string
 	Vars         Vars
 	Env          Vars
+	Dotenv       []string
 	Silent       bool
 	Method       string
 	Prefix       string
 	IgnoreError  bool `yaml:"ignore_error"`
+	Cache        bool
+	WatchIgnore  []string `yaml:"watch_ignore"`
+	Watch        []string
+
+	// Location is the path of the Taskfile the task is defined on
+	Location string `yaml:"-"`
+	// IncludeDir is the dir of the include the task comes from, if given.
+	// It's expanded when the task is compiled, and is relative to
+	// IncludeParent unless it's absolute then. Relative task dirs are
+	// relative to it
+	IncludeDir    string `yaml:"-"`
+	IncludeParent string `yaml:"-"`
 }
@@ -2,14 +2,38 @@ package taskfile
 
 // Taskfile represents a Taskfile.yml
 type Taskfile struct {
-	Version    string
-	Expansions int
-	Output     string
-	Includes   map[string]string
-	Vars       Vars
-	Env        Vars
-	Tasks      Tasks
+	Version          string
+	Expansions       int
+	Output           string
+	Includes         map[string]*Include
+	Vars             Vars
+	Env              Vars
+	Dotenv           []string
+	Tasks            Tasks
 	ResetVarsOnRerun bool
+	Watch            Watch
+	Profiles         map[string]*Profile
+
+	// Aliases are the namespace aliases of the included Taskfiles, mapped
+	// to their namespaces
+	Aliases map[string]string
+
+	// Duplicates are the tasks defined on more than one of the included
+	// Taskfiles. The last definition is kept
+	Duplicates []DuplicateTask
+
+	// Merges report the Taskfiles merged into this one, in order
+	Merges []MergeReport
+
+	// Files are the paths of the files this Taskfile was read from,
+	// including the included ones
+	Files []string
+}
+
+// DuplicateTask is a task defined on more than one Taskfile
+type DuplicateTask struct {
+	Task  string
+	Files []string
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler interface
@@ -20,14 +44,17 @@ func (tf *Taskfile) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	}
 
 	var taskfile struct {
-		Version    string
-		Expansions int
-		Output     string
-		Includes   map[string]string
-		Vars       Vars
-		Env        Vars
-		Tasks      Tasks
+		Version          string
+		Expansions       int
+		Output           string
+		Includes         map[string]*Include
+		Vars             Vars
+		Env              Vars
+		Dotenv           []string
+		Tasks            Tasks
 		ResetVarsOnRerun bool `yaml:"reset-vars-on-rerun"`
+		Watch            Watch
+		Profiles         map[string]*Profile
 	}
 
 	taskfile.ResetVarsOnRerun = true
@@ -41,8 +68,11 @@ func (tf *Taskfile) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	tf.Includes = taskfile.Includes
 	tf.Vars = taskfile.Vars
 	tf.Env = taskfile.Env
+	tf.Dotenv = taskfile.Dotenv
 	tf.Tasks = taskfile.Tasks
 	tf.ResetVarsOnRerun = taskfile.ResetVarsOnRerun
+	tf.Watch = taskfile.Watch
+	tf.Profiles = taskfile.Profiles
 	if tf.Expansions <= 0 {
 		tf.Expansions = 2
 	}
@@ -58,3 +58,126 @@ vars:
 		assert.Equal(t, test.expected, test.v)
 	}
 }
+
+func TestIncludeParse(t *testing.T) {
+	const (
+		yamlShort = `./included`
+		yamlMap   = `
+taskfile: ./included/Taskfile.yml
+dir: ./included
+optional: true
+vars:
+  NAME: value
+aliases: [inc]
+`
+	)
+	tests := []struct {
+		content  string
+		expected *taskfile.Include
+	}{
+		{
+			yamlShort,
+			&taskfile.Include{Taskfile: "./included", ShortSyntax: true},
+		},
+		{
+			yamlMap,
+			&taskfile.Include{
+				Taskfile: "./included/Taskfile.yml",
+				Dir:      "./included",
+				Optional: true,
+				Vars:     taskfile.Vars{"NAME": taskfile.Var{Static: "value"}},
+				Aliases:  []string{"inc"},
+			},
+		},
+	}
+	for _, test := range tests {
+		var include taskfile.Include
+		assert.NoError(t, yaml.Unmarshal([]byte(test.content), &include))
+		assert.Equal(t, test.expected, &include)
+	}
+
+	var include taskfile.Include
+	assert.Error(t, yaml.Unmarshal([]byte(`dir: ./included`), &include))
+}
+
+func TestResolveAlias(t *testing.T) {
+	tf := &taskfile.Taskfile{Aliases: map[string]string{
+		"s":     "svc",
+		"svc:d": "svc:db",
+	}}
+	assert.Equal(t, "svc:build", tf.ResolveAlias("s:build"))
+	assert.Equal(t, "svc:db:migrate", tf.ResolveAlias("s:d:migrate"))
+	assert.Equal(t, "svc:db:migrate", tf.ResolveAlias("svc:db:migrate"))
+	assert.Equal(t, "sx:build", tf.ResolveAlias("sx:build"))
+	assert.Equal(t, "s", tf.ResolveAlias("s"))
+}
+
+func TestMerge(t *testing.T) {
+	t1 := &taskfile.Taskfile{
+		Version: "2",
+		Vars:    taskfile.Vars{"FOO": taskfile.Var{Static: "root"}},
+		Env:     taskfile.Vars{"ENV": taskfile.Var{Static: "root"}},
+		Tasks: taskfile.Tasks{
+			"build": &taskfile.Task{Task: "build"},
+		},
+		Files: []string{"Taskfile.yml"},
+	}
+	t2 := &taskfile.Taskfile{
+		Version:  "2.6",
+		Includes: map[string]*taskfile.Include{"docs:api": {Taskfile: "api"}},
+		Vars:     taskfile.Vars{"FOO": taskfile.Var{Static: "included"}},
+		Env: taskfile.Vars{
+			"ENV":   taskfile.Var{Static: "included"},
+			"OTHER": taskfile.Var{Static: "included"},
+		},
+		Tasks: taskfile.Tasks{
+			"build":      &taskfile.Task{Task: "build"},
+			"docs:serve": &taskfile.Task{Task: "docs:serve"},
+		},
+		Files: []string{"docs/Taskfile.yml"},
+	}
+
+	assert.NoError(t, taskfile.Merge(t1, t2))
+	assert.Equal(t, "2", t1.Version)
+	assert.Equal(t, "root", t1.Vars["FOO"].Static)
+	assert.Equal(t, t2.Vars, t1.Tasks["docs:serve"].TaskfileVars)
+	assert.Equal(t, "included", t1.Env["ENV"].Static)
+	assert.Contains(t, t1.Includes, "docs:api")
+	assert.Equal(t, []taskfile.MergeReport{{
+		File:            "docs/Taskfile.yml",
+		Into:            "Taskfile.yml",
+		Tasks:           []string{"docs:serve"},
+		OverriddenTasks: []string{"build"},
+		OverriddenEnv:   []string{"ENV"},
+	}}, t1.Merges)
+
+	// the version of the root Taskfile is kept
+	t1.Version = "2.1"
+	assert.NoError(t, taskfile.Merge(t1, &taskfile.Taskfile{Version: "2.6"}))
+	assert.Equal(t, "2.1", t1.Version)
+
+	assert.Error(t, taskfile.Merge(t1, &taskfile.Taskfile{Version: "1"}))
+}
+
+func TestMergeWatchIgnore(t *testing.T) {
+	t1 := &taskfile.Taskfile{
+		Version: "2",
+		Watch:   taskfile.Watch{Ignore: []string{"*.tmp"}},
+		Files:   []string{"Taskfile.yml"},
+	}
+	t2 := &taskfile.Taskfile{
+		Version: "2",
+		Watch:   taskfile.Watch{Ignore: []string{"*.log", "/build", "out/", "gen/*.go", "!keep.log", "# comment"}},
+		Files:   []string{"services/api/Taskfile.yml"},
+	}
+	assert.NoError(t, taskfile.Merge(t1, t2))
+	assert.Equal(t, []string{
+		"*.tmp",
+		"services/api/**/*.log",
+		"services/api/build",
+		"services/api/**/out/",
+		"services/api/gen/*.go",
+		"!services/api/**/keep.log",
+		"# comment",
+	}, t1.Watch.Ignore)
+}
@@ -0,0 +1,25 @@
+package taskfile
+
+import (
+	"time"
+)
+
+// Watch holds the Taskfile settings of the watch mode
+type Watch struct {
+	// Ignore are gitignore-style patterns, relative to the Taskfile
+	// directory, of paths that never trigger a rerun
+	Ignore []string
+	// Debounce is how long to wait for changes to settle before rerunning,
+	// e.g. "1s". Zero means the default
+	Debounce time.Duration
+	// Signal is sent to the commands still running when a task reruns or
+	// watch mode exits, e.g. "SIGTERM". Defaults to SIGINT
+	Signal string
+	// GracePeriod is how long commands have to stop after Signal before
+	// being killed. Defaults to 2s
+	GracePeriod time.Duration `yaml:"grace_period"`
+	// OnSuccess and OnFailure are commands run after each watched run,
+	// depending on its result
+	OnSuccess string `yaml:"on_success"`
+	OnFailure string `yaml:"on_failure"`
+}
@@ -0,0 +1,12 @@
+// Package term puts terminals in cbreak mode, where key presses are read as
+// they are typed, without echo, while signals like Ctrl-C and the output
+// processing keep working.
+package term
+
+import (
+	"errors"
+)
+
+// ErrUnsupported is returned by CBreak on platforms where it's not
+// implemented
+var ErrUnsupported = errors.New("term: cbreak mode is not supported on this platform")
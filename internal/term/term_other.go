@@ -0,0 +1,25 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+// +build !linux,!darwin,!dragonfly,!freebsd,!netbsd,!openbsd
+
+package term
+
+import (
+	"io"
+	"os"
+)
+
+// IsTerminal reports whether fd is a terminal. It always returns false on
+// this platform
+func IsTerminal(fd uintptr) bool {
+	return false
+}
+
+// CBreak is not supported on this platform
+func CBreak(fd uintptr) (restore func() error, err error) {
+	return nil, ErrUnsupported
+}
+
+// NewReader is not supported on this platform
+func NewReader(f *os.File) (io.ReadCloser, error) {
+	return nil, ErrUnsupported
+}
@@ -0,0 +1,58 @@
+package term_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/leiyangyou/task/v2/internal/term"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotTerminal(t *testing.T) {
+	f, err := ioutil.TempFile("", "task-term")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	assert.False(t, term.IsTerminal(f.Fd()))
+	_, err = term.CBreak(f.Fd())
+	assert.Error(t, err)
+}
+
+func TestReaderClose(t *testing.T) {
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+
+	reader, err := term.NewReader(r)
+	if err == term.ErrUnsupported {
+		t.Skip(err)
+	}
+	assert.NoError(t, err)
+
+	done := make(chan error)
+	go func() {
+		_, err := reader.Read(make([]byte, 1))
+		done <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, reader.Close())
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Close didn't stop the pending Read")
+	}
+
+	// the original file is blocking again
+	_, err = w.Write([]byte("a"))
+	assert.NoError(t, err)
+	n, err := r.Read(make([]byte, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
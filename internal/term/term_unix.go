@@ -0,0 +1,84 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+// +build linux darwin dragonfly freebsd netbsd openbsd
+
+package term
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func getTermios(fd uintptr) (*syscall.Termios, error) {
+	var t syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlGetTermios, uintptr(unsafe.Pointer(&t))); errno != 0 {
+		return nil, errno
+	}
+	return &t, nil
+}
+
+func setTermios(fd uintptr, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlSetTermios, uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// IsTerminal reports whether fd is a terminal
+func IsTerminal(fd uintptr) bool {
+	_, err := getTermios(fd)
+	return err == nil
+}
+
+// CBreak puts the terminal in cbreak mode, returning a func that restores
+// its previous state
+func CBreak(fd uintptr) (restore func() error, err error) {
+	old, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	t := *old
+	t.Lflag &^= syscall.ICANON | syscall.ECHO
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+	if err = setTermios(fd, &t); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return setTermios(fd, old)
+	}, nil
+}
+
+// NewReader returns a reader of the terminal f whose Close stops a pending
+// Read. It reads from a non-blocking duplicate of f, which is made blocking
+// again on Close
+func NewReader(f *os.File) (io.ReadCloser, error) {
+	// Fd makes f blocking, so it must not be called after this
+	orig := f.Fd()
+	fd, err := syscall.Dup(int(orig))
+	if err != nil {
+		return nil, err
+	}
+	if err = syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return &reader{File: os.NewFile(uintptr(fd), f.Name()), fd: orig}, nil
+}
+
+type reader struct {
+	*os.File
+	fd uintptr
+}
+
+func (r *reader) Close() error {
+	err := r.File.Close()
+	// the duplicate shares the non-blocking flag with the original
+	if err2 := syscall.SetNonblock(int(r.fd), false); err == nil {
+		err = err2
+	}
+	return err
+}
@@ -3,6 +3,7 @@ package task
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/leiyangyou/task/v2/internal/execext"
 	"github.com/leiyangyou/task/v2/internal/status"
@@ -27,6 +28,94 @@ func (e *Executor) Status(ctx context.Context, calls ...taskfile.Call) error {
 	return nil
 }
 
+// TaskStatus is the up-to-date status of a task, as given by StatusReport
+type TaskStatus struct {
+	Task     string   `json:"task"`
+	UpToDate bool     `json:"up_to_date"`
+	Reasons  []string `json:"reasons,omitempty"`
+	// Deps are the tasks the task depends on or calls, on recursive reports
+	Deps []string `json:"deps,omitempty"`
+}
+
+// StatusReport checks if each of the given tasks is up-to-date, also
+// checking their dependencies if recursive is set. Unlike Status, it doesn't
+// stop on the first task that's not up-to-date, and doesn't persist any
+// state on disk.
+func (e *Executor) StatusReport(ctx context.Context, recursive bool, calls ...taskfile.Call) ([]TaskStatus, error) {
+	var report []TaskStatus
+	visited := make(map[string]bool)
+
+	check := func(t *taskfile.Task) error {
+		if visited[t.Task] {
+			return nil
+		}
+		visited[t.Task] = true
+
+		upToDate, reasons := e.checkTaskStatus(ctx, t)
+		s := TaskStatus{Task: t.Task, UpToDate: upToDate, Reasons: reasons}
+		if recursive {
+			for _, d := range t.Deps {
+				s.Deps = append(s.Deps, d.Task)
+			}
+			for _, c := range t.Cmds {
+				if c.Task != "" {
+					s.Deps = append(s.Deps, c.Task)
+				}
+			}
+		}
+		report = append(report, s)
+		return nil
+	}
+
+	for _, call := range calls {
+		if recursive {
+			if err := e.walkTask(call, check); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		t, err := e.CompiledTask(call)
+		if err != nil {
+			return nil, err
+		}
+		_ = check(t)
+	}
+	return report, nil
+}
+
+// PrintStatusTree prints a report of StatusReport as a tree, from the given
+// tasks down to their dependencies
+func (e *Executor) PrintStatusTree(report []TaskStatus, calls ...taskfile.Call) {
+	statuses := make(map[string]TaskStatus, len(report))
+	for _, s := range report {
+		statuses[s.Task] = s
+	}
+
+	var print func(task string, depth int, ancestors map[string]bool)
+	print = func(task string, depth int, ancestors map[string]bool) {
+		s, ok := statuses[task]
+		if !ok || ancestors[task] {
+			return
+		}
+		line := "up-to-date"
+		if !s.UpToDate {
+			line = "not up-to-date: " + strings.Join(s.Reasons, "; ")
+		}
+		e.Logger.Outf("%s%s: %s", strings.Repeat("  ", depth), s.Task, line)
+
+		ancestors[task] = true
+		for _, d := range s.Deps {
+			print(d, depth+1, ancestors)
+		}
+		delete(ancestors, task)
+	}
+
+	for _, call := range calls {
+		print(e.Taskfile.ResolveAlias(call.Task), 0, make(map[string]bool))
+	}
+}
+
 func (e *Executor) isTaskUpToDate(ctx context.Context, t *taskfile.Task) (bool, error) {
 	hasStatus := len(t.Status) > 0
 
@@ -40,7 +129,7 @@ func (e *Executor) isTaskUpToDate(ctx context.Context, t *taskfile.Task) (bool,
 	hasSource := len(t.Sources) > 0
 
 	if hasSource {
-		checker, err := e.getStatusChecker(t)
+		checker, err := e.getStatusChecker(t, e.Dry)
 
 		if err != nil {
 			return false, err
@@ -56,15 +145,58 @@ func (e *Executor) isTaskUpToDate(ctx context.Context, t *taskfile.Task) (bool,
 	return hasStatus || hasSource, nil
 }
 
+// checkTaskStatus is like isTaskUpToDate, but doesn't stop on the first
+// check that fails, so all the reasons for a task not being up-to-date are
+// returned. It never persists the state of the checkers
+func (e *Executor) checkTaskStatus(ctx context.Context, t *taskfile.Task) (bool, []string) {
+	var reasons []string
+
+	if len(t.Status) == 0 && len(t.Sources) == 0 {
+		return false, []string{"task has no sources or status"}
+	}
+
+	for _, s := range t.Status {
+		err := execext.RunCommand(ctx, &execext.RunCommandOptions{
+			Command: s,
+			Dir:     t.Dir,
+			Env:     getEnviron(t),
+		})
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf(`status command "%s" failed: %v`, s, err))
+		}
+	}
+
+	if len(t.Sources) > 0 {
+		method := t.Method
+		if method == "" {
+			method = "timestamp"
+		}
+
+		checker, err := e.getStatusChecker(t, true)
+		if err == nil {
+			var upToDate bool
+			upToDate, err = checker.IsUpToDate()
+			if err == nil && !upToDate {
+				reasons = append(reasons, fmt.Sprintf("sources are not up-to-date (method %s)", method))
+			}
+		}
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("unable to check sources (method %s): %v", method, err))
+		}
+	}
+
+	return len(reasons) == 0, reasons
+}
+
 func (e *Executor) statusOnError(t *taskfile.Task) error {
-	checker, err := e.getStatusChecker(t)
+	checker, err := e.getStatusChecker(t, e.Dry)
 	if err != nil {
 		return err
 	}
 	return checker.OnError()
 }
 
-func (e *Executor) getStatusChecker(t *taskfile.Task) (status.Checker, error) {
+func (e *Executor) getStatusChecker(t *taskfile.Task, dry bool) (status.Checker, error) {
 	switch t.Method {
 	case "", "timestamp":
 		return &status.Timestamp{
@@ -77,7 +209,14 @@ func (e *Executor) getStatusChecker(t *taskfile.Task) (status.Checker, error) {
 			Dir:     t.Dir,
 			Task:    t.Task,
 			Sources: t.Sources,
-			Dry:     e.Dry,
+			Dry:     dry,
+		}, nil
+	case "git":
+		return &status.Git{
+			Dir:     t.Dir,
+			Task:    t.Task,
+			Sources: t.Sources,
+			Dry:     dry,
 		}, nil
 	case "none":
 		return status.None{}, nil
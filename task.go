@@ -8,15 +8,20 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/leiyangyou/task/v2/internal/cache"
 	"github.com/leiyangyou/task/v2/internal/compiler"
 	compilerv1 "github.com/leiyangyou/task/v2/internal/compiler/v1"
 	compilerv2 "github.com/leiyangyou/task/v2/internal/compiler/v2"
 	"github.com/leiyangyou/task/v2/internal/execext"
+	"github.com/leiyangyou/task/v2/internal/ignore"
 	"github.com/leiyangyou/task/v2/internal/logger"
 	"github.com/leiyangyou/task/v2/internal/output"
+	"github.com/leiyangyou/task/v2/internal/remote"
 	"github.com/leiyangyou/task/v2/internal/summary"
 	"github.com/leiyangyou/task/v2/internal/taskfile"
 	"github.com/leiyangyou/task/v2/internal/taskfile/read"
@@ -41,6 +46,41 @@ type Executor struct {
 	Dry      bool
 	Summary  bool
 
+	// Interval is how long watch mode waits for changes to settle before
+	// rerunning. Zero means the Taskfile setting, or 500ms
+	Interval time.Duration
+	// WatchPoll makes watch mode scan the file system every Interval
+	// instead of relying on native notifications
+	WatchPoll bool
+	// OnSuccess and OnFailure are commands run after each watched run,
+	// overriding the ones of the Taskfile
+	OnSuccess string
+	OnFailure string
+
+	// Entrypoint is the path of the Taskfile to read. If not given, it's
+	// looked for on Dir and its parent directories. Dir defaults to its
+	// directory
+	Entrypoint string
+	// Global makes Task read the user Taskfile, on ~/.config/task, instead
+	// of the one on Dir. Its tasks still run on Dir
+	Global bool
+	// UserTasks includes the tasks of the user Taskfile on the "user"
+	// namespace
+	UserTasks bool
+
+	// Offline makes remote includes use only the local cache
+	Offline bool
+
+	// Profile selects a profile of the Taskfile, overriding its vars and
+	// env and adding its includes
+	Profile string
+	// EnvFiles are dotenv files read after the ones of the Taskfile
+	EnvFiles []string
+
+	// AllowDuplicates makes tasks defined on more than one included
+	// Taskfile a warning instead of an error
+	AllowDuplicates bool
+
 	Stdin  io.Reader
 	Stdout io.Writer
 	Stderr io.Writer
@@ -50,7 +90,31 @@ type Executor struct {
 	Output      output.Output
 	OutputStyle string
 
+	// Cache stores generated files of tasks with "cache: true". Defaults to
+	// a local cache on cache.DefaultDir(), backed by RemoteCache if given,
+	// created when a task first uses it
+	Cache              cache.Store
+	RemoteCache        string
+	RemoteCacheTimeout time.Duration
+	CacheReadOnly      bool
+	defaultCache       *defaultCache
+
+	// stateMu guards the fields set from the Taskfile below, and Taskfile,
+	// Dir, Compiler and Output, which are replaced when the Taskfile is
+	// reloaded on watch mode
+	stateMu *sync.RWMutex
+
 	taskvars taskfile.Vars
+	// taskfileDir is the directory of the Taskfile, which is Dir unless
+	// Global is set
+	taskfileDir string
+	// dotenvFiles are the dotenv files of the Taskfile and EnvFiles
+	dotenvFiles []string
+
+	watchIgnore *ignore.Tree
+	stopSignal  os.Signal
+	gracePeriod time.Duration
+	console     *watchConsole
 
 	taskCallCount map[string]*int32
 	mkdirMutexMap map[string]*sync.Mutex
@@ -58,8 +122,12 @@ type Executor struct {
 
 // Run runs Task
 func (e *Executor) Run(ctx context.Context, calls ...taskfile.Call) error {
-	// check if given tasks exist
-	for _, c := range calls {
+	// check if given tasks exist, and call them by their namespace
+	// instead of an alias
+	calls = append([]taskfile.Call(nil), calls...)
+	for i, c := range calls {
+		c.Task = e.Taskfile.ResolveAlias(c.Task)
+		calls[i] = c
 		if _, ok := e.Taskfile.Tasks[c.Task]; !ok {
 			// FIXME: move to the main package
 			e.PrintTasksHelp()
@@ -73,7 +141,7 @@ func (e *Executor) Run(ctx context.Context, calls ...taskfile.Call) error {
 	}
 
 	if e.Watch {
-		return e.watchTasks(calls...)
+		return e.watchTasks(ctx, calls...)
 	} else {
 		for _, c := range calls {
 			if err := e.RunTask(ctx, c); err != nil {
@@ -87,16 +155,6 @@ func (e *Executor) Run(ctx context.Context, calls ...taskfile.Call) error {
 
 // Setup setups Executor's internal state
 func (e *Executor) Setup() error {
-	var err error
-	e.Taskfile, err = read.Taskfile(filepath.Join(e.Dir, "Taskfile.yml"), nil)
-	if err != nil {
-		return err
-	}
-	e.taskvars, err = read.Taskvars(e.Dir)
-	if err != nil {
-		return err
-	}
-
 	if e.Stdin == nil {
 		e.Stdin = os.Stdin
 	}
@@ -111,10 +169,53 @@ func (e *Executor) Setup() error {
 		Stderr:  e.Stderr,
 		Verbose: e.Verbose,
 	}
+	e.defaultCache = &defaultCache{}
+	e.stateMu = &sync.RWMutex{}
+
+	return e.loadTaskfile()
+}
+
+// loadTaskfile reads the Taskfile and the Taskvars, and setups everything
+// that depends on them. The Executor is left untouched on errors, so it can
+// be called again to reload them
+func (e *Executor) loadTaskfile() error {
+	path, runDir, err := e.resolveTaskfile()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	fetcher, err := e.remoteFetcher(dir)
+	if err != nil {
+		return err
+	}
+	opts := read.Options{Remote: fetcher, Profile: e.Profile}
+	tf, err := read.TaskfileWithOptions(path, nil, opts)
+	if err != nil {
+		return err
+	}
+	if e.UserTasks && !e.Global {
+		if err = e.mergeUserTasks(tf, opts); err != nil {
+			return err
+		}
+	}
+	taskvars, err := read.Taskvars(dir)
+	if err != nil {
+		return err
+	}
+	var profileVars taskfile.Vars
+	if profile := tf.Profiles[e.Profile]; e.Profile != "" && profile != nil {
+		profileVars = profile.Vars
+		if tf.Env == nil {
+			tf.Env = make(taskfile.Vars)
+		}
+		for k, v := range profile.Env {
+			tf.Env[k] = v
+		}
+	}
 
-	v, err := strconv.ParseFloat(e.Taskfile.Version, 64)
+	v, err := strconv.ParseFloat(tf.Version, 64)
 	if err != nil {
-		return fmt.Errorf(`task: Could not parse taskfile version "%s": %v`, e.Taskfile.Version, err)
+		return fmt.Errorf(`task: Could not parse taskfile version "%s": %v`, tf.Version, err)
 	}
 	// consider as equal to the greater version if round
 	if v == 2.0 {
@@ -128,47 +229,72 @@ func (e *Executor) Setup() error {
 		return fmt.Errorf(`task: Taskfile versions greater than v2.6 not implemented in the version of Task`)
 	}
 
+	var c compiler.Compiler
 	if v < 2 {
-		e.Compiler = &compilerv1.CompilerV1{
-			Dir:    e.Dir,
-			Vars:   e.taskvars,
+		c = &compilerv1.CompilerV1{
+			Dir:    runDir,
+			Vars:   taskvars,
 			Logger: e.Logger,
 		}
 	} else { // v >= 2
-		e.Compiler = &compilerv2.CompilerV2{
-			Dir:          e.Dir,
-			Taskvars:     e.taskvars,
-			TaskfileVars: e.Taskfile.Vars,
-			Expansions:   e.Taskfile.Expansions,
+		c = &compilerv2.CompilerV2{
+			Dir:          runDir,
+			Taskvars:     taskvars,
+			TaskfileVars: tf.Vars,
+			ProfileVars:  profileVars,
+			Expansions:   tf.Expansions,
 			Logger:       e.Logger,
 		}
 	}
 
-	if v < 2.1 && e.Taskfile.Output != "" {
+	dotenv, dotenvFiles, err := e.readDotenv(tf, c, dir)
+	if err != nil {
+		return err
+	}
+	if c2, ok := c.(*compilerv2.CompilerV2); ok {
+		c2.Dotenv = dotenv
+	}
+	// the env of the Taskfile overrides its dotenv files
+	for k, v := range dotenv {
+		if tf.Env == nil {
+			tf.Env = make(taskfile.Vars)
+		}
+		if _, ok := tf.Env[k]; !ok {
+			tf.Env[k] = v
+		}
+	}
+
+	if err = e.checkDuplicates(tf); err != nil {
+		return err
+	}
+	e.reportMerges(tf)
+
+	if v < 2.1 && tf.Output != "" {
 		return fmt.Errorf(`task: Taskfile option "output" is only available starting on Taskfile version v2.1`)
 	}
-	if v < 2.2 && len(e.Taskfile.Includes) > 0 {
+	if v < 2.2 && len(tf.Includes) > 0 {
 		return fmt.Errorf(`task: Including Taskfiles is only available starting on Taskfile version v2.2`)
 	}
 
 	if e.OutputStyle != "" {
-		e.Taskfile.Output = e.OutputStyle
+		tf.Output = e.OutputStyle
 	}
-	switch e.Taskfile.Output {
+	var out output.Output
+	switch tf.Output {
 	case "", "interleaved":
-		e.Output = output.Interleaved{}
+		out = output.Interleaved{}
 	case "group":
-		e.Output = output.Group{}
+		out = output.Group{}
 	case "prefixed":
-		e.Output = output.Prefixed{}
+		out = output.Prefixed{}
 	default:
-		return fmt.Errorf(`task: output option "%s" not recognized`, e.Taskfile.Output)
+		return fmt.Errorf(`task: output option "%s" not recognized`, tf.Output)
 	}
 
 	if v <= 2.1 {
 		err := errors.New(`task: Taskfile option "ignore_error" is only available starting on Taskfile version v2.1`)
 
-		for _, task := range e.Taskfile.Tasks {
+		for _, task := range tf.Tasks {
 			if task.IgnoreError {
 				return err
 			}
@@ -181,13 +307,25 @@ func (e *Executor) Setup() error {
 	}
 
 	if v < 2.6 {
-		for _, task := range e.Taskfile.Tasks {
+		for _, task := range tf.Tasks {
 			if len(task.Preconditions) > 0 {
 				return errors.New(`task: Task option "preconditions" is only available starting on Taskfile version v2.6`)
 			}
 		}
 	}
 
+	if err = fetcher.Lock.Save(); err != nil {
+		return err
+	}
+
+	e.Dir = runDir
+	e.Taskfile = tf
+	e.taskfileDir = dir
+	e.dotenvFiles = dotenvFiles
+	e.taskvars = taskvars
+	e.Compiler = c
+	e.Output = out
+
 	e.taskCallCount = make(map[string]*int32, len(e.Taskfile.Tasks))
 	e.mkdirMutexMap = make(map[string]*sync.Mutex, len(e.Taskfile.Tasks))
 	for k := range e.Taskfile.Tasks {
@@ -197,8 +335,111 @@ func (e *Executor) Setup() error {
 	return nil
 }
 
+// resolveTaskfile returns the path of the Taskfile to read, and the
+// directory its tasks run on. The Taskfile is the user one if Global is
+// set, Entrypoint if given, or the one on Dir or the nearest of its parent
+// directories, in which case the tasks run on its directory
+func (e *Executor) resolveTaskfile() (path, runDir string, err error) {
+	switch {
+	case e.Global:
+		dir, err := read.UserDir()
+		if err != nil {
+			return "", "", err
+		}
+		path, ok := taskfile.FindInDir(dir)
+		if !ok {
+			return "", "", fmt.Errorf(`task: No user Taskfile found on "%s"`, dir)
+		}
+		return path, e.Dir, nil
+
+	case e.Entrypoint != "":
+		if _, err := os.Stat(e.Entrypoint); err != nil {
+			return "", "", fmt.Errorf(`task: Taskfile "%s" not found`, e.Entrypoint)
+		}
+		if e.Dir == "" {
+			return e.Entrypoint, filepath.Dir(e.Entrypoint), nil
+		}
+		return e.Entrypoint, e.Dir, nil
+
+	default:
+		path, err := read.FindTaskfile(e.Dir)
+		if err != nil {
+			return "", "", err
+		}
+		if filepath.Dir(path) != filepath.Clean(e.Dir) {
+			// found on a parent directory
+			return path, filepath.Dir(path), nil
+		}
+		return path, e.Dir, nil
+	}
+}
+
+// remoteFetcher returns the fetcher of the remote includes, caching them on
+// the includes directory of the local cache, and pinning them on the lock
+// file next to the Taskfile. Without a cache directory, only the remote
+// includes fail
+func (e *Executor) remoteFetcher(taskfileDir string) (*remote.Fetcher, error) {
+	lock, err := remote.ReadLock(filepath.Join(taskfileDir, remote.LockFile))
+	if err != nil {
+		return nil, err
+	}
+	var dir string
+	if cacheDir, err := cache.DefaultDir(); err == nil {
+		dir = filepath.Join(cacheDir, "includes")
+	}
+	return &remote.Fetcher{
+		Dir:     dir,
+		Offline: e.Offline,
+		Lock:    lock,
+	}, nil
+}
+
+// checkDuplicates fails if tasks are defined on more than one included
+// Taskfile, or only warns about them if AllowDuplicates is set
+func (e *Executor) checkDuplicates(tf *taskfile.Taskfile) error {
+	if len(tf.Duplicates) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(tf.Duplicates))
+	for i, d := range tf.Duplicates {
+		messages[i] = fmt.Sprintf(`task: Task "%s" is defined on both "%s" and "%s"`, d.Task, d.Files[0], d.Files[1])
+	}
+	if e.AllowDuplicates {
+		for _, m := range messages {
+			e.Logger.Errf("%s", m)
+		}
+		return nil
+	}
+	return errors.New(strings.Join(messages, "\n"))
+}
+
+// reportMerges prints what each Taskfile merged changed on verbose mode
+func (e *Executor) reportMerges(tf *taskfile.Taskfile) {
+	for _, m := range tf.Merges {
+		e.Logger.VerboseErrf(`task: Merged "%s" into "%s"`, m.File, m.Into)
+		if len(m.Tasks) > 0 {
+			e.Logger.VerboseErrf(`task:   added tasks: %s`, strings.Join(m.Tasks, ", "))
+		}
+		if len(m.OverriddenTasks) > 0 {
+			e.Logger.VerboseErrf(`task:   overridden tasks: %s`, strings.Join(m.OverriddenTasks, ", "))
+		}
+		if len(m.OverriddenEnv) > 0 {
+			e.Logger.VerboseErrf(`task:   overridden env: %s`, strings.Join(m.OverriddenEnv, ", "))
+		}
+	}
+}
+
 // RunTask runs a task by its name
 func (e *Executor) RunTask(ctx context.Context, call taskfile.Call) error {
+	// on watch mode, runs are shared between reruns
+	if m := memoFromContext(ctx); m != nil {
+		return m.run(ctx, call, e.runTask)
+	}
+	return e.runTask(ctx, call)
+}
+
+func (e *Executor) runTask(ctx context.Context, call taskfile.Call) error {
 	t, err := e.CompiledTask(call)
 	if err != nil {
 		return &taskRunError{call.Task, err}
@@ -211,7 +452,7 @@ func (e *Executor) RunTask(ctx context.Context, call taskfile.Call) error {
 		return err
 	}
 
-	if !e.Force {
+	if !e.isForced(ctx) {
 		preCondMet, err := e.areTaskPreconditionsMet(ctx, t)
 		if err != nil {
 			return err
@@ -234,6 +475,11 @@ func (e *Executor) RunTask(ctx context.Context, call taskfile.Call) error {
 		e.Logger.Errf("task: cannot make directory %q: %v", t.Dir, err)
 	}
 
+	cacheKey := e.cacheKey(ctx, t)
+	if cacheKey != "" && e.restoreFromCache(t, cacheKey) {
+		return nil
+	}
+
 	for i := range t.Cmds {
 		if err := e.runCommand(ctx, t, call, i); err != nil {
 			if err2 := e.statusOnError(t); err2 != nil {
@@ -242,12 +488,17 @@ func (e *Executor) RunTask(ctx context.Context, call taskfile.Call) error {
 
 			if execext.IsExitError(err) && t.IgnoreError {
 				e.Logger.VerboseErrf("task: task error ignored: %v", err)
+				cacheKey = ""
 				continue
 			}
 
 			return &taskRunError{t.Task, err}
 		}
 	}
+
+	if cacheKey != "" {
+		e.saveToCache(t, cacheKey)
+	}
 	return nil
 }
 
@@ -323,10 +574,13 @@ func (e *Executor) runCommand(ctx context.Context, t *taskfile.Task, call taskfi
 		err := execext.RunCommand(ctx, &execext.RunCommandOptions{
 			Command: cmd.Cmd,
 			Dir:     t.Dir,
-			Env:     getEnviron(t),
+			Env:     changedFilesEnviron(ctx, getEnviron(t)),
 			Stdin:   e.Stdin,
 			Stdout:  stdOut,
 			Stderr:  stdErr,
+
+			Signal:      e.stopSignal,
+			KillTimeout: e.gracePeriod,
 		})
 		if execext.IsExitError(err) && cmd.IgnoreError {
 			e.Logger.VerboseErrf("task: command error ignored: %v", err)
@@ -5,12 +5,18 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/leiyangyou/task/v2"
+	"github.com/leiyangyou/task/v2/internal/cache"
 	"github.com/leiyangyou/task/v2/internal/taskfile"
 
 	"github.com/mitchellh/go-homedir"
@@ -273,6 +279,41 @@ func TestStatus(t *testing.T) {
 	}
 }
 
+func TestStatusReport(t *testing.T) {
+	const dir = "testdata/status_report"
+
+	e := &task.Executor{
+		Dir:    dir,
+		Stdout: ioutil.Discard,
+		Stderr: ioutil.Discard,
+	}
+	assert.NoError(t, e.Setup())
+
+	report, err := e.StatusReport(context.Background(), false, taskfile.Call{Task: "stale"}, taskfile.Call{Task: "up-to-date"})
+	assert.NoError(t, err)
+	assert.Equal(t, []task.TaskStatus{
+		{Task: "stale", UpToDate: false, Reasons: []string{`status command "test 1 = 0" failed: exit status 1`}},
+		{Task: "up-to-date", UpToDate: true},
+	}, report)
+
+	report, err = e.StatusReport(context.Background(), true, taskfile.Call{Task: "default"})
+	assert.NoError(t, err)
+	assert.Equal(t, []task.TaskStatus{
+		{Task: "up-to-date", UpToDate: true},
+		{Task: "stale", UpToDate: false, Reasons: []string{`status command "test 1 = 0" failed: exit status 1`}, Deps: []string{"up-to-date"}},
+		{Task: "no-status", UpToDate: false, Reasons: []string{"task has no sources or status"}},
+		{Task: "default", UpToDate: true, Deps: []string{"stale", "no-status"}},
+	}, report)
+
+	var buff bytes.Buffer
+	e.Logger.Stdout = &buff
+	e.PrintStatusTree(report, taskfile.Call{Task: "default"})
+	assert.Equal(t, "default: up-to-date\n"+
+		"  stale: not up-to-date: status command \"test 1 = 0\" failed: exit status 1\n"+
+		"    up-to-date: up-to-date\n"+
+		"  no-status: not up-to-date: task has no sources or status\n", buff.String())
+}
+
 func TestPrecondition(t *testing.T) {
 	const dir = "testdata/precondition"
 
@@ -406,6 +447,176 @@ func TestStatusChecksum(t *testing.T) {
 	assert.Equal(t, `task: Task "build" is up to date`+"\n", buff.String())
 }
 
+func TestClean(t *testing.T) {
+	const dir = "testdata/clean"
+
+	var (
+		generated = []string{"out/foo.txt", "out/bar.txt"}
+		checksum  = filepath.Join(dir, ".task/checksum/build")
+		outside   = filepath.Join(dir, "../clean_outside.txt")
+	)
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+	}
+	assert.NoError(t, e.Setup())
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+	_, err := os.Stat(checksum)
+	assert.NoError(t, err)
+
+	e.Dry = true
+	assert.NoError(t, e.Clean(taskfile.Call{Task: "build"}))
+	assert.Equal(t, "task: Would remove testdata/clean/out/bar.txt\n"+
+		"task: Would remove testdata/clean/out/foo.txt\n"+
+		"task: Would remove testdata/clean/.task/checksum/build\n", buff.String())
+	for _, f := range generated {
+		_, err := os.Stat(filepath.Join(dir, f))
+		assert.NoError(t, err, "dry mode should not remove files")
+	}
+
+	e.Dry = false
+	assert.NoError(t, e.Clean(taskfile.Call{Task: "build"}))
+	for _, f := range append(generated, ".task/checksum/build") {
+		_, err := os.Stat(filepath.Join(dir, f))
+		assert.Error(t, err, "file should be removed")
+	}
+
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "outside"}))
+	defer os.Remove(outside)
+	assert.Error(t, e.Clean(taskfile.Call{Task: "outside"}), "files outside of the Taskfile directory need --force")
+	_, err = os.Stat(outside)
+	assert.NoError(t, err)
+
+	e.Force = true
+	assert.NoError(t, e.Clean(taskfile.Call{Task: "outside"}))
+	_, err = os.Stat(outside)
+	assert.Error(t, err)
+
+	// a symlinked directory can't be used to escape the Taskfile directory
+	target, err := ioutil.TempDir("", "task-clean")
+	assert.NoError(t, err)
+	defer os.RemoveAll(target)
+	linked := filepath.Join(dir, "linked")
+	_ = os.Remove(linked)
+	assert.NoError(t, os.Symlink(target, linked))
+	defer os.Remove(linked)
+
+	e.Force = false
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "linked"}))
+	assert.Error(t, e.Clean(taskfile.Call{Task: "linked"}), "files outside of the Taskfile directory need --force")
+	_, err = os.Stat(filepath.Join(target, "linked.txt"))
+	assert.NoError(t, err)
+}
+
+func TestCache(t *testing.T) {
+	const dir = "testdata/cache"
+
+	cacheDir, err := ioutil.TempDir("", "task-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	_ = os.RemoveAll(filepath.Join(dir, "out"))
+	_ = os.Remove(filepath.Join(dir, "runs.txt"))
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: &buff,
+		Stderr: &buff,
+		Silent: true,
+		Cache:  &cache.Local{Dir: cacheDir},
+	}
+	assert.NoError(t, e.Setup())
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+
+	// simulates a checkout where the generated file is gone
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "out")))
+
+	e.Silent = false
+	buff.Reset()
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build"}))
+	assert.Equal(t, `task: Task "build" restored from cache`+"\n", buff.String())
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "out", "generated.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "generated\n", string(b))
+	b, err = ioutil.ReadFile(filepath.Join(dir, "runs.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "run\n", string(b), "commands should have run only once")
+
+	// a different var value means a different fingerprint
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "out")))
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build", Vars: taskfile.Vars{"CONTENT": {Static: "other"}}}))
+	b, err = ioutil.ReadFile(filepath.Join(dir, "runs.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "run\nrun\n", string(b))
+}
+
+func TestCacheDir(t *testing.T) {
+	const dir = "testdata/cache"
+
+	cacheDir, err := ioutil.TempDir("", "task-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+	defer os.Setenv("TASK_CACHE_DIR", os.Getenv("TASK_CACHE_DIR"))
+	assert.NoError(t, os.Setenv("TASK_CACHE_DIR", cacheDir))
+
+	_ = os.RemoveAll(filepath.Join(dir, "out-dir"))
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: ioutil.Discard,
+		Stderr: ioutil.Discard,
+	}
+	assert.NoError(t, e.Setup())
+	files, err := ioutil.ReadDir(cacheDir)
+	assert.NoError(t, err)
+	assert.Empty(t, files, "the cache should only be created when a task uses it")
+
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build-dir"}))
+	files, err = ioutil.ReadDir(cacheDir)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+
+	// directories are restored with their contents
+	assert.NoError(t, os.RemoveAll(filepath.Join(dir, "out-dir")))
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "build-dir"}))
+	b, err := ioutil.ReadFile(filepath.Join(dir, "out-dir", "sub", "generated.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "generated\n", string(b))
+}
+
+func TestCacheNoSources(t *testing.T) {
+	const dir = "testdata/cache"
+
+	cacheDir, err := ioutil.TempDir("", "task-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	_ = os.RemoveAll(filepath.Join(dir, "out-no-sources"))
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: ioutil.Discard,
+		Stderr: ioutil.Discard,
+		Cache:  &cache.Local{Dir: cacheDir},
+	}
+	assert.NoError(t, e.Setup())
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "no-sources"}))
+	files, err := ioutil.ReadDir(cacheDir)
+	assert.NoError(t, err)
+	assert.Empty(t, files, "tasks without sources should not be cached")
+
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "no-sources"}))
+	b, err := ioutil.ReadFile(filepath.Join(dir, "out-no-sources", "runs.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "run\nrun\n", string(b))
+}
+
 func TestInit(t *testing.T) {
 	const dir = "testdata/init"
 	var file = filepath.Join(dir, "Taskfile.yml")
@@ -557,6 +768,154 @@ func TestIncludes(t *testing.T) {
 	tt.Run(t)
 }
 
+func TestIncludesRich(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_rich",
+		Target:    "default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"svc/svc.txt": "svc",
+			"shared.txt":  "shared",
+		},
+	}
+	tt.Run(t)
+
+	tt.Target = "s:gen"
+	tt.Files = map[string]string{"svc/svc.txt": "svc"}
+	tt.Run(t)
+
+	// the include dir is expanded before being joined to the parent
+	_ = os.RemoveAll("testdata/includes_rich/out")
+	tt.Target = "templated:gen"
+	tt.Files = map[string]string{"out/shared.txt": "shared"}
+	tt.Run(t)
+
+	outDir, err := ioutil.TempDir("", "task-include-dir")
+	assert.NoError(t, err)
+	defer os.RemoveAll(outDir)
+
+	var buff bytes.Buffer
+	e := task.Executor{Dir: "testdata/includes_rich", Stdout: &buff, Stderr: &buff}
+	assert.NoError(t, e.Setup())
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "templated:gen", Vars: taskfile.Vars{"OUT_DIR": {Static: outDir}}}))
+	b, err := ioutil.ReadFile(filepath.Join(outDir, "shared.txt"))
+	assert.NoError(t, err, "an absolute include dir should not be joined to the parent")
+	assert.Equal(t, "shared\n", string(b))
+}
+
+func TestIncludesGlob(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/includes_glob",
+		Target:    "default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"services/api/api.txt": "api",
+			"services/web/web.txt": "web",
+			"lint.txt":             "lint",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestIncludesGlobConflict(t *testing.T) {
+	const dir = "testdata/includes_glob_conflict"
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: ioutil.Discard,
+		Stderr: ioutil.Discard,
+	}
+	err := e.Setup()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `"api"`)
+	assert.Contains(t, err.Error(), filepath.Join(dir, "a", "api", "Taskfile.yml"))
+	assert.Contains(t, err.Error(), filepath.Join(dir, "b", "api", "Taskfile.yml"))
+}
+
+func TestIncludesCycle(t *testing.T) {
+	e := task.Executor{
+		Dir:    "testdata/includes_cycle",
+		Stdout: ioutil.Discard,
+		Stderr: ioutil.Discard,
+	}
+	err := e.Setup()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "task: Include cycle: ")
+	assert.Contains(t, err.Error(), filepath.Join("includes_cycle", "one", "Taskfile.yml")+" -> ")
+}
+
+func TestIncludesDuplicates(t *testing.T) {
+	const dir = "testdata/includes_duplicates"
+
+	var buff bytes.Buffer
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: ioutil.Discard,
+		Stderr: &buff,
+	}
+	err := e.Setup()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), fmt.Sprintf(`task: Task "build" is defined on both "%s" and "%s"`,
+		filepath.Join(dir, "Taskfile.yml"), filepath.Join(dir, "Common.yml")))
+
+	e.AllowDuplicates = true
+	assert.NoError(t, e.Setup())
+	assert.Contains(t, buff.String(), `task: Task "build" is defined on both`)
+}
+
+func TestIncludesRemote(t *testing.T) {
+	dir, err := ioutil.TempDir("", "task-remote")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer os.Setenv("TASK_CACHE_DIR", os.Getenv("TASK_CACHE_DIR"))
+	assert.NoError(t, os.Setenv("TASK_CACHE_DIR", filepath.Join(dir, "cache")))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "version: '2'\ntasks:\n  gen:\n    cmds:\n      - echo remote > remote.txt\n")
+	}))
+	defer server.Close()
+
+	project := filepath.Join(dir, "project")
+	assert.NoError(t, os.MkdirAll(project, 0755))
+	taskfileContent := fmt.Sprintf("version: '2'\nincludes:\n  ci: %s/Taskfile.yml\n", server.URL)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(project, "Taskfile.yml"), []byte(taskfileContent), 0644))
+
+	e := task.Executor{
+		Dir:    project,
+		Stdout: ioutil.Discard,
+		Stderr: ioutil.Discard,
+	}
+	assert.NoError(t, e.Setup())
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "ci:gen"}))
+
+	// remote tasks run on the directory of the including Taskfile
+	data, err := ioutil.ReadFile(filepath.Join(project, "remote.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "remote\n", string(data))
+	_, err = os.Stat(filepath.Join(project, "Taskfile.lock"))
+	assert.NoError(t, err)
+
+	server.Close()
+	e.Offline = true
+	assert.NoError(t, e.Setup())
+}
+
+func TestIncludesFiles(t *testing.T) {
+	const dir = "testdata/includes"
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: ioutil.Discard,
+		Stderr: ioutil.Discard,
+	}
+	assert.NoError(t, e.Setup())
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "Taskfile.yml"),
+		filepath.Join(dir, "Taskfile2.yml"),
+		filepath.Join(dir, "included", "Taskfile.yml"),
+	}, e.Taskfile.Files)
+}
+
 func TestIncludesEmptyMain(t *testing.T) {
 	tt := fileContentTest{
 		Dir:       "testdata/includes_empty",
@@ -595,6 +954,186 @@ func TestIncludesCallingRoot(t *testing.T) {
 	tt.Run(t)
 }
 
+func TestTaskfileNames(t *testing.T) {
+	const dir = "testdata/taskfile_dist"
+
+	tests := []struct {
+		executor task.Executor
+		file     string
+		content  string
+	}{
+		{task.Executor{Dir: dir}, filepath.Join(dir, "file.txt"), "dist"},
+		{task.Executor{Dir: filepath.Join(dir, "override")}, filepath.Join(dir, "override", "file.txt"), "override"},
+		{task.Executor{Entrypoint: filepath.Join(dir, "Custom.yml")}, filepath.Join(dir, "file.txt"), "custom"},
+		// tasks run on the directory of a Taskfile found on a parent
+		{task.Executor{Dir: filepath.Join(dir, "sub")}, filepath.Join(dir, "file.txt"), "dist"},
+	}
+	for _, test := range tests {
+		_ = os.Remove(test.file)
+
+		e := test.executor
+		e.Stdout = ioutil.Discard
+		e.Stderr = ioutil.Discard
+		assert.NoError(t, e.Setup())
+		assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+
+		data, err := ioutil.ReadFile(test.file)
+		assert.NoError(t, err)
+		assert.Equal(t, test.content, strings.TrimSpace(string(data)))
+	}
+}
+
+func TestTaskfileDistMerge(t *testing.T) {
+	// the tasks of Taskfile.dist.yml not overridden by Taskfile.yml are kept
+	tt := fileContentTest{
+		Dir:       "testdata/taskfile_dist/override",
+		Target:    "shared",
+		TrimSpace: true,
+		Files: map[string]string{
+			"shared.txt": "shared",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestLocalOverride(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/local_override",
+		Target:    "default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"greet.txt": "hi local local",
+			"build.txt": "local build",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestProfiles(t *testing.T) {
+	const dir = "testdata/profiles"
+
+	readFile := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		assert.NoError(t, err)
+		return strings.TrimSpace(string(b))
+	}
+	for _, f := range []string{"target.txt", "extra.txt"} {
+		_ = os.Remove(filepath.Join(dir, f))
+	}
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: ioutil.Discard,
+		Stderr: ioutil.Discard,
+	}
+	assert.NoError(t, e.Setup())
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	assert.Equal(t, "local dev", readFile("target.txt"))
+	assert.Error(t, e.Run(context.Background(), taskfile.Call{Task: "extra:hello"}))
+
+	e.Profile = "ci"
+	assert.NoError(t, e.Setup())
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	assert.Equal(t, "ci ci", readFile("target.txt"))
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "extra:hello"}))
+	assert.Equal(t, "ci", readFile("extra.txt"))
+
+	// call vars take precedence over the profile
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "call"}))
+	assert.Equal(t, "call ci", readFile("target.txt"))
+
+	e.Profile = "missing"
+	assert.Error(t, e.Setup())
+}
+
+func TestDotenv(t *testing.T) {
+	tt := fileContentTest{
+		Dir:       "testdata/dotenv",
+		Target:    "default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"dotenv.txt": `dotenv dev "quoted" taskfile dotenv`,
+		},
+	}
+	tt.Run(t)
+
+	tt = fileContentTest{
+		Dir:       "testdata/dotenv",
+		Target:    "task-dotenv",
+		TrimSpace: true,
+		Files: map[string]string{
+			// like env, vars of the task dotenv files override the ones of
+			// the Taskfile, but not the ones of the task
+			"task_dotenv.txt": "task dotenv task task dotenv task task",
+		},
+	}
+	tt.Run(t)
+
+	tt = fileContentTest{
+		Dir:       "testdata/dotenv",
+		Target:    "included:default",
+		TrimSpace: true,
+		Files: map[string]string{
+			"included.txt": "included included",
+		},
+	}
+	tt.Run(t)
+}
+
+func TestEnvFiles(t *testing.T) {
+	const dir = "testdata/dotenv"
+	_ = os.Remove(filepath.Join(dir, "dotenv.txt"))
+
+	e := task.Executor{
+		Dir:      dir,
+		EnvFiles: []string{filepath.Join(dir, "cli.env")},
+		Stdout:   ioutil.Discard,
+		Stderr:   ioutil.Discard,
+	}
+	assert.NoError(t, e.Setup())
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	b, err := ioutil.ReadFile(filepath.Join(dir, "dotenv.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, `cli dev "quoted" taskfile cli`, strings.TrimSpace(string(b)))
+
+	e.EnvFiles = []string{filepath.Join(dir, "missing.env")}
+	assert.Error(t, e.Setup())
+}
+
+func TestUserTasks(t *testing.T) {
+	const dir = "testdata/user_tasks/project"
+	configDir, err := filepath.Abs("testdata/user_tasks/config")
+	assert.NoError(t, err)
+	defer os.Setenv("XDG_CONFIG_HOME", os.Getenv("XDG_CONFIG_HOME"))
+	assert.NoError(t, os.Setenv("XDG_CONFIG_HOME", configDir))
+
+	helloFile := filepath.Join(dir, "hello.txt")
+	_ = os.Remove(helloFile)
+
+	e := task.Executor{
+		Dir:    dir,
+		Stdout: ioutil.Discard,
+		Stderr: ioutil.Discard,
+	}
+	assert.NoError(t, e.Setup())
+	assert.Error(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+
+	// user tasks run on the project directory
+	e.UserTasks = true
+	assert.NoError(t, e.Setup())
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "default"}))
+	_, err = os.Stat(helloFile)
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.Remove(helloFile))
+	e.UserTasks = false
+	e.Global = true
+	assert.NoError(t, e.Setup())
+	assert.NoError(t, e.Run(context.Background(), taskfile.Call{Task: "hello"}))
+	_, err = os.Stat(helloFile)
+	assert.NoError(t, err)
+}
+
 func TestSummary(t *testing.T) {
 	const dir = "testdata/summary"
 
@@ -678,3 +1217,336 @@ func TestWhenDirAttributeItCreatesMissingAndRunsInThatDir(t *testing.T) {
 	// Clean-up after ourselves only if no error.
 	_ = os.Remove(toBeCreated)
 }
+
+// syncBuffer is a bytes.Buffer safe to be written by concurrent runs
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// watchTest runs the Taskfile of a testdata directory on watch mode, from
+// a copy of the directory, so tests can change its files
+type watchTest struct {
+	t      *testing.T
+	dir    string
+	output *syncBuffer
+	cancel context.CancelFunc
+	done   chan error
+}
+
+func startWatch(t *testing.T, fixture string, calls ...taskfile.Call) *watchTest {
+	return startWatchExecutor(t, fixture, func(*task.Executor) {}, calls...)
+}
+
+// startWatchExecutor is like startWatch, but lets the test configure the
+// Executor before it's set up
+func startWatchExecutor(t *testing.T, fixture string, configure func(*task.Executor), calls ...taskfile.Call) *watchTest {
+	dir, err := ioutil.TempDir("", "task-watch")
+	assert.NoError(t, err)
+	dir, err = filepath.EvalSymlinks(dir)
+	assert.NoError(t, err)
+
+	files, err := ioutil.ReadDir(fixture)
+	assert.NoError(t, err)
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(fixture, f.Name()))
+		assert.NoError(t, err)
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, f.Name()), b, 0644))
+	}
+
+	w := &watchTest{t: t, dir: dir, output: &syncBuffer{}, done: make(chan error, 1)}
+	e := &task.Executor{
+		Dir:      dir,
+		Watch:    true,
+		Verbose:  true,
+		Interval: 50 * time.Millisecond,
+		Stdin:    &bytes.Buffer{},
+		Stdout:   w.output,
+		Stderr:   w.output,
+	}
+	configure(e)
+	assert.NoError(t, e.Setup())
+
+	var ctx context.Context
+	ctx, w.cancel = context.WithCancel(context.Background())
+	go func() { w.done <- e.Run(ctx, calls...) }()
+	return w
+}
+
+// write writes a file of the watched directory
+func (w *watchTest) write(name, content string) {
+	assert.NoError(w.t, ioutil.WriteFile(filepath.Join(w.dir, name), []byte(content), 0644))
+}
+
+// read returns the content of a file of the watched directory, or an empty
+// string if it doesn't exist
+func (w *watchTest) read(name string) string {
+	b, _ := ioutil.ReadFile(filepath.Join(w.dir, name))
+	return string(b)
+}
+
+// waitFor waits until cond is true, failing the test after a while
+func (w *watchTest) waitFor(what string, cond func() bool) {
+	w.t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			w.t.Fatalf("timed out waiting for %s, output:\n%s", what, w.output)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// waitForFile waits until a file of the watched directory has the given
+// content
+func (w *watchTest) waitForFile(name, content string) {
+	w.t.Helper()
+	w.waitFor(fmt.Sprintf("%s to be %q", name, content), func() bool {
+		return w.read(name) == content
+	})
+}
+
+// waitForOutput waits until the output contains s
+func (w *watchTest) waitForOutput(s string) {
+	w.t.Helper()
+	w.waitFor(fmt.Sprintf("%q on the output", s), func() bool {
+		return strings.Contains(w.output.String(), s)
+	})
+}
+
+// settle gives the watchers time to be set up, or to be sure nothing
+// happens after a change
+func (w *watchTest) settle() {
+	time.Sleep(300 * time.Millisecond)
+}
+
+func (w *watchTest) stop() {
+	w.cancel()
+	select {
+	case err := <-w.done:
+		assert.NoError(w.t, err)
+	case <-time.After(10 * time.Second):
+		w.t.Error("timed out waiting for watch mode to stop")
+	}
+	_ = os.RemoveAll(w.dir)
+}
+
+func TestWatchReload(t *testing.T) {
+	w := startWatch(t, "testdata/watch_reload", taskfile.Call{Task: "default"})
+	defer w.stop()
+
+	w.waitForFile("out.txt", "first\n")
+	w.waitForOutput("for the Taskfile")
+	w.settle()
+
+	w.write("Taskfile.yml", "version: '2'\n\ntasks:\n  default:\n    cmds:\n      - echo second > out.txt\n")
+	w.waitForFile("out.txt", "second\n")
+
+	// an invalid Taskfile is reported, and the previous one kept
+	w.write("Taskfile.yml", "version: '2'\n\ntasks: [\n")
+	w.waitForOutput("task: Unable to reload the Taskfile")
+
+	w.write("Taskfile.yml", "version: '2'\n\ntasks:\n  default:\n    cmds:\n      - echo third > out.txt\n")
+	w.waitForFile("out.txt", "third\n")
+}
+
+func TestWatchChangedFiles(t *testing.T) {
+	w := startWatch(t, "testdata/watch_changed_files", taskfile.Call{Task: "default"})
+	defer w.stop()
+
+	w.waitForFile("root.txt", "\n")
+	w.settle()
+
+	w.write("a b.src", "a")
+	w.write("c.src", "c")
+	w.waitForFile("root.txt", "a b.src\nc.src\n")
+	w.waitForFile("dep.txt", "a b.src c.src\n")
+	w.waitForFile("env.txt", "a b.src\nc.src")
+}
+
+func TestWatchIndex(t *testing.T) {
+	w := startWatch(t, "testdata/watch_index", taskfile.Call{Task: "default"})
+	defer w.stop()
+
+	w.waitForFile("runs.log", "run\n")
+	w.settle()
+	assert.Equal(t, "run\n", w.read("runs.log"), "generated files should not trigger reruns")
+
+	w.write("ignored.src", "ignored")
+	w.settle()
+	assert.Equal(t, "run\n", w.read("runs.log"), "ignored files should not trigger reruns")
+
+	w.write("a.src", "changed")
+	w.waitForFile("runs.log", "run\nrun\n")
+	w.settle()
+	assert.Equal(t, "run\nrun\n", w.read("runs.log"))
+}
+
+func TestWatchCyclicDep(t *testing.T) {
+	for _, task := range []string{"task-1", "both"} {
+		t.Run(task, func(t *testing.T) {
+			w := startWatch(t, "testdata/watch_cyclic", taskfile.Call{Task: task})
+			defer w.stop()
+
+			w.waitForOutput("cyclic dependency detected")
+		})
+	}
+}
+
+func TestWatchSubtree(t *testing.T) {
+	w := startWatch(t, "testdata/watch_subtree", taskfile.Call{Task: "default"})
+	defer w.stop()
+
+	// like when not watching, a dep called twice on a run runs twice
+	w.waitForFile("default.log", "run\n")
+	assert.Equal(t, "run\nrun\n", w.read("shared.log"))
+	w.settle()
+
+	w.write("a.src", "changed")
+	w.waitForFile("default.log", "run\nrun\n")
+	w.settle()
+	assert.Equal(t, "run\nrun\n", w.read("a.log"), "the changed task should rerun")
+	assert.Equal(t, "run\n", w.read("b.log"), "an unaffected sibling should not rerun")
+	assert.Equal(t, "run\nrun\n", w.read("shared.log"), "an unaffected dep should not rerun")
+}
+
+func TestWatchGlobs(t *testing.T) {
+	w := startWatch(t, "testdata/watch_globs", taskfile.Call{Task: "default"}, taskfile.Call{Task: "status"})
+	defer w.stop()
+
+	w.waitForFile("runs.log", "run\n")
+	w.waitForFile("status.log", "run\n")
+	w.settle()
+
+	// watch globs force a rerun, even if the sources didn't change
+	w.write("file.watched", "changed")
+	w.waitForFile("runs.log", "run\nrun\n")
+
+	w.write("a.src", "changed")
+	w.waitForFile("runs.log", "run\nrun\nrun\n")
+
+	// tasks with status and no sources as well
+	w.write("file.status", "changed")
+	w.waitForFile("status.log", "run\nrun\n")
+	assert.Equal(t, "run\nrun\nrun\n", w.read("runs.log"))
+}
+
+func TestWatchHooks(t *testing.T) {
+	w := startWatch(t, "testdata/watch_hooks", taskfile.Call{Task: "default"})
+	defer w.stop()
+
+	w.waitFor("the on_success hook", func() bool {
+		return regexp.MustCompile(`^default success [0-9.]+[µm]?s \n$`).MatchString(w.read("success.log"))
+	})
+	w.settle()
+
+	w.write("fail.src", "fail")
+	w.waitFor("the on_failure hook", func() bool {
+		return strings.HasPrefix(w.read("failure.log"), "default failure ") &&
+			strings.Contains(w.read("failure.log"), "exit status 1")
+	})
+	w.waitForOutput("task: on_failure hook failed: exit status 1")
+}
+
+func TestWatchHooksFlags(t *testing.T) {
+	w := startWatchExecutor(t, "testdata/watch_hooks", func(e *task.Executor) {
+		e.OnSuccess = `echo "$TASK_NAME $TASK_STATUS" > flag-success.log`
+		e.OnFailure = `echo "$TASK_NAME $TASK_STATUS" > flag-failure.log`
+	}, taskfile.Call{Task: "default"})
+	defer w.stop()
+
+	// the flags have precedence over the Taskfile
+	w.waitForFile("flag-success.log", "default success\n")
+	w.settle()
+
+	w.write("fail.src", "fail")
+	w.waitForFile("flag-failure.log", "default failure\n")
+	assert.Equal(t, "", w.read("success.log"))
+	assert.Equal(t, "", w.read("failure.log"))
+}
+
+func TestWatchProcessGroup(t *testing.T) {
+	w := startWatch(t, "testdata/watch_process_group", taskfile.Call{Task: "default"})
+	defer w.stop()
+
+	w.waitForFile("runs.log", "run\n")
+	w.waitFor("the child to start", func() bool { return w.read("child.pid") != "" })
+	pid := w.read("child.pid")
+	w.settle()
+
+	// the child of the previous run is gone once the rerun starts
+	w.write("a.src", "changed")
+	w.waitForFile("runs.log", "run\nrun\n")
+	w.waitFor("the new child to start", func() bool { return w.read("child.pid") != pid })
+}
+
+func TestWatchGitignoreNegation(t *testing.T) {
+	w := startWatch(t, "testdata/watch_gitignore", taskfile.Call{Task: "default"})
+	defer w.stop()
+
+	w.waitForFile("runs.log", "run\n")
+	w.settle()
+
+	w.write("other.gen", "ignored")
+	w.settle()
+	assert.Equal(t, "run\n", w.read("runs.log"), "gitignored files should not trigger reruns")
+
+	// the Taskfile patterns have precedence over .gitignore
+	w.write("keep.gen", "changed")
+	w.waitForFile("runs.log", "run\nrun\n")
+}
+
+func TestWatchResetVars(t *testing.T) {
+	w := startWatch(t, "testdata/watch_vars", taskfile.Call{Task: "default"})
+	defer w.stop()
+
+	w.waitForFile("a.log", "a\n")
+	w.waitForFile("b.log", "b\n")
+	w.settle()
+	assert.Equal(t, "run\n", w.read("a-sh.log"))
+	assert.Equal(t, "run\n", w.read("b-sh.log"))
+
+	// only the vars of the tasks that run again are evaluated again
+	w.write("a.src", "changed")
+	w.waitForFile("a.log", "a\na\n")
+	w.settle()
+	assert.Equal(t, "run\nrun\n", w.read("a-sh.log"))
+	assert.Equal(t, "run\n", w.read("b-sh.log"))
+	assert.Equal(t, "b\n", w.read("b.log"))
+}
+
+func TestWatchIntervalReload(t *testing.T) {
+	w := startWatchExecutor(t, "testdata/watch_interval", func(e *task.Executor) {
+		e.Interval = 0
+		e.WatchPoll = true
+	}, taskfile.Call{Task: "default"})
+	defer w.stop()
+
+	w.waitForFile("runs.log", "run\n")
+	w.settle()
+
+	w.write("Taskfile.yml", strings.Replace(w.read("Taskfile.yml"), "debounce: 1s", "debounce: 50ms", 1))
+	w.waitForOutput("task: Taskfile changed, reloading")
+	// the scan scheduled with the previous interval still has to happen
+	time.Sleep(1200 * time.Millisecond)
+
+	start := time.Now()
+	w.write("a.src", "changed")
+	w.waitForFile("runs.log", "run\nrun\n")
+	assert.True(t, time.Since(start) < 900*time.Millisecond, "the reloaded interval should be used, took %v", time.Since(start))
+}
@@ -0,0 +1,10 @@
+# writes "alive" instead of "run" if the child of the previous run is
+# still running
+if [ -f child.pid ]; then
+  pid=$(cat child.pid)
+  if kill -0 "$pid" 2>/dev/null && ! grep -qs ') Z' "/proc/$pid/stat"; then
+    echo alive >> runs.log
+    exit 0
+  fi
+fi
+echo run >> runs.log
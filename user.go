@@ -0,0 +1,37 @@
+package task
+
+import (
+	"fmt"
+
+	"github.com/leiyangyou/task/v2/internal/taskfile"
+	"github.com/leiyangyou/task/v2/internal/taskfile/read"
+)
+
+// userNamespace is the namespace of the user tasks merged into projects
+const userNamespace = "user"
+
+// mergeUserTasks includes the tasks of the user Taskfile, if it exists, on
+// the user namespace. Like the ones of short syntax includes, they run on
+// Dir
+func (e *Executor) mergeUserTasks(tf *taskfile.Taskfile, opts read.Options) error {
+	dir, err := read.UserDir()
+	if err != nil {
+		return err
+	}
+	path, ok := taskfile.FindInDir(dir)
+	if !ok {
+		return nil
+	}
+
+	// the profile is the one of the project, not of the user Taskfile
+	opts.Profile = ""
+	userTaskfile, err := read.TaskfileWithOptions(path, nil, opts, userNamespace)
+	if err != nil {
+		return err
+	}
+	// user tasks are shared by projects on different minor versions
+	if err = taskfile.Merge(tf, userTaskfile); err != nil {
+		return fmt.Errorf(`task: The user Taskfile can't be merged: %v`, err)
+	}
+	return nil
+}
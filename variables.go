@@ -11,7 +11,7 @@ import (
 // CompiledTask returns a copy of a task, but replacing variables in almost all
 // properties using the Go template package.
 func (e *Executor) CompiledTask(call taskfile.Call) (*taskfile.Task, error) {
-	origTask, ok := e.Taskfile.Tasks[call.Task]
+	origTask, ok := e.Taskfile.Tasks[e.Taskfile.ResolveAlias(call.Task)]
 	if !ok {
 		return nil, &taskNotFoundError{call.Task}
 	}
@@ -20,6 +20,18 @@ func (e *Executor) CompiledTask(call taskfile.Call) (*taskfile.Task, error) {
 	if err != nil {
 		return nil, err
 	}
+	dotenv, err := e.taskDotenv(origTask, vars)
+	if err != nil {
+		return nil, err
+	}
+	if len(dotenv) > 0 {
+		// like for env, the dotenv files of the task override the Taskfile
+		// vars, but not the vars of the call and of the task
+		call.Vars = dotenv.Merge(call.Vars)
+		if vars, err = e.Compiler.GetVariables(origTask, call); err != nil {
+			return nil, err
+		}
+	}
 	r := templater.Templater{Vars: vars}
 
 	new := taskfile.Task{
@@ -35,11 +47,24 @@ func (e *Executor) CompiledTask(call taskfile.Call) (*taskfile.Task, error) {
 		Method:      r.Replace(origTask.Method),
 		Prefix:      r.Replace(origTask.Prefix),
 		IgnoreError: origTask.IgnoreError,
+		Cache:       origTask.Cache,
+		WatchIgnore: r.ReplaceSlice(origTask.WatchIgnore),
+		Watch:       r.ReplaceSlice(origTask.Watch),
 	}
 	new.Dir, err = execext.Expand(new.Dir)
 	if err != nil {
 		return nil, err
 	}
+	if origTask.IncludeDir != "" && !filepath.IsAbs(new.Dir) {
+		includeDir, err := execext.Expand(r.Replace(origTask.IncludeDir))
+		if err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(includeDir) {
+			includeDir = filepath.Join(origTask.IncludeParent, includeDir)
+		}
+		new.Dir = filepath.Join(includeDir, new.Dir)
+	}
 	if e.Dir != "" && !filepath.IsAbs(new.Dir) {
 		new.Dir = filepath.Join(e.Dir, new.Dir)
 	}
@@ -47,10 +72,13 @@ func (e *Executor) CompiledTask(call taskfile.Call) (*taskfile.Task, error) {
 		new.Prefix = new.Task
 	}
 
-	new.Env = make(taskfile.Vars, len(e.Taskfile.Env)+len(origTask.Env))
+	new.Env = make(taskfile.Vars, len(e.Taskfile.Env)+len(dotenv)+len(origTask.Env))
 	for k, v := range r.ReplaceVars(e.Taskfile.Env) {
 		new.Env[k] = v
 	}
+	for k, v := range dotenv {
+		new.Env[k] = v
+	}
 	for k, v := range r.ReplaceVars(origTask.Env) {
 		new.Env[k] = v
 	}
@@ -11,30 +11,239 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/leiyangyou/task/v2/internal/compiler"
+	"github.com/leiyangyou/task/v2/internal/execext"
+	"github.com/leiyangyou/task/v2/internal/ignore"
+	"github.com/leiyangyou/task/v2/internal/poll"
 	"github.com/leiyangyou/task/v2/internal/status"
 	"github.com/leiyangyou/task/v2/internal/taskfile"
+	"github.com/leiyangyou/task/v2/internal/taskfile/read"
 	"github.com/rjeczalik/notify"
 )
 
 type void struct{}
 
-const debounceTime = 500 * time.Millisecond
+const defaultWatchInterval = 500 * time.Millisecond
 
-func (e *Executor) runCalls(calls ...taskfile.Call) (ctx context.Context, cancel context.CancelFunc) {
-	ctx, cancel = context.WithCancel(context.Background())
-	for _, c := range calls {
-		c := c
-		go func() {
-			if err := e.RunTask(ctx, c); err != nil && !isContextError(err) {
-				e.Logger.Errf("%v", err)
-			}
-		}()
+// watchInterval returns the debounce time of watch mode, which is also the
+// scan interval when polling. The flag has precedence over the Taskfile
+func (e *Executor) watchInterval() time.Duration {
+	if e.Interval > 0 {
+		return e.Interval
+	}
+	e.stateMu.RLock()
+	defer e.stateMu.RUnlock()
+	if e.Taskfile.Watch.Debounce > 0 {
+		return e.Taskfile.Watch.Debounce
+	}
+	return defaultWatchInterval
+}
+
+// startRun runs the call in background, sharing the runs of its tasks with
+// the previous runs through the memo. The files that triggered the run are
+// available to all its tasks as the CHANGED_FILES var and the
+// TASK_CHANGED_FILES environment variable. The tasks whose keys are in
+// forced run even when up-to-date, and all of them do when force is set.
+// The run uses a snapshot of the Executor, so it's not affected by reloads
+// of the Taskfile
+func (e *Executor) startRun(m *runMemo, changed []string, forced []string, force bool, call taskfile.Call) {
+	e = e.snapshot()
+	changedFiles := e.changedFiles(changed)
+	e.Compiler = changedFilesCompiler{Compiler: e.Compiler, changedFiles: changedFiles}
+	ctx := context.WithValue(context.Background(), changedFilesKey{}, changedFiles)
+	ctx = context.WithValue(ctx, runMemoKey{}, m)
+	ctx = m.nextGeneration(ctx)
+	if force {
+		ctx = context.WithValue(ctx, forceKey{}, true)
+	}
+	if len(forced) > 0 {
+		keys := make(map[string]void, len(forced))
+		for _, key := range forced {
+			keys[key] = void{}
+		}
+		ctx = context.WithValue(ctx, forcedKeysKey{}, keys)
+	}
+
+	go func() {
+		start := time.Now()
+		err := e.RunTask(ctx, call)
+		if isContextError(err) {
+			// a rerun superseded this run
+			return
+		}
+		if err != nil {
+			e.Logger.Errf("%v", err)
+		}
+		e.watchRunFinished(call.Task, time.Since(start), err)
+	}()
+}
+
+// watchRunFinished reports the result of a watched run
+func (e *Executor) watchRunFinished(task string, duration time.Duration, err error) {
+	if e.console != nil {
+		e.console.printStatus(task, duration, err)
+	}
+	e.runWatchHook(task, duration, err)
+}
+
+// runWatchHook runs the on_success or on_failure command, if any, with the
+// result of the run as environment variables. The flags have precedence
+// over the Taskfile
+func (e *Executor) runWatchHook(task string, duration time.Duration, err error) {
+	name, command, status, errText := "on_success", e.OnSuccess, "success", ""
+	if command == "" {
+		command = e.Taskfile.Watch.OnSuccess
+	}
+	if err != nil {
+		name, command, status, errText = "on_failure", e.OnFailure, "failure", err.Error()
+		if command == "" {
+			command = e.Taskfile.Watch.OnFailure
+		}
+	}
+	if command == "" {
+		return
+	}
+
+	environ := append(os.Environ(),
+		"TASK_NAME="+task,
+		"TASK_STATUS="+status,
+		"TASK_DURATION="+duration.Round(time.Millisecond).String(),
+		"TASK_ERROR="+errText,
+	)
+	e.Logger.VerboseErrf(`task: running %s hook: "%s"`, name, command)
+	if err := execext.RunCommand(context.Background(), &execext.RunCommandOptions{
+		Command: command,
+		Dir:     e.Dir,
+		Env:     environ,
+		Stdout:  e.Stdout,
+		Stderr:  e.Stderr,
+	}); err != nil {
+		e.Logger.Errf("task: %s hook failed: %v", name, err)
+	}
+}
+
+type forceKey struct{}
+
+// forcedKeysKey holds the runKeys of the tasks forced on a watch run, e.g.
+// the ones owning files changed on their watch globs
+type forcedKeysKey struct{}
+
+// forcedRunKey is set on the context of each run of runMemo, telling
+// whether that task is forced, so its dependencies don't inherit it
+type forcedRunKey struct{}
+
+// isForced reports whether the tasks run with ctx should run even when
+// up-to-date, e.g. after pressing "f" on watch mode
+func (e *Executor) isForced(ctx context.Context) bool {
+	forced, _ := ctx.Value(forceKey{}).(bool)
+	forcedRun, _ := ctx.Value(forcedRunKey{}).(bool)
+	return e.Force || forced || forcedRun
+}
+
+type changedFilesKey struct{}
+
+// changedFiles joins the changed paths with newlines, so paths with spaces
+// can be told apart, relative to the Taskfile directory when possible
+func (e *Executor) changedFiles(changed []string) string {
+	dir, _ := filepath.Abs(e.Dir)
+	files := make([]string, len(changed))
+	for i, path := range changed {
+		files[i] = path
+		if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+			files[i] = rel
+		}
+	}
+	return strings.Join(files, "\n")
+}
+
+// changedFilesCompiler adds the CHANGED_FILES var to the calls of all the
+// tasks of a watch run, below the vars of the tasks
+type changedFilesCompiler struct {
+	compiler.Compiler
+	changedFiles string
+}
+
+func (c changedFilesCompiler) GetVariables(t *taskfile.Task, call taskfile.Call) (taskfile.Vars, error) {
+	call.Vars = call.Vars.Merge(taskfile.Vars{"CHANGED_FILES": taskfile.Var{Static: c.changedFiles}})
+	return c.Compiler.GetVariables(t, call)
+}
+
+// changedFilesEnviron adds TASK_CHANGED_FILES to environ on watch runs
+func changedFilesEnviron(ctx context.Context, environ []string) []string {
+	changedFiles, ok := ctx.Value(changedFilesKey{}).(string)
+	if !ok {
+		return environ
 	}
-	return
+	if environ == nil {
+		environ = os.Environ()
+	}
+	return append(environ, "TASK_CHANGED_FILES="+changedFiles)
+}
+
+func (e *Executor) setupStopSignal() error {
+	e.stopSignal = os.Interrupt
+	if e.Taskfile.Watch.Signal != "" {
+		sig, err := execext.ParseSignal(e.Taskfile.Watch.Signal)
+		if err != nil {
+			return err
+		}
+		e.stopSignal = sig
+	}
+	e.gracePeriod = e.Taskfile.Watch.GracePeriod
+	return nil
+}
+
+// defaultWatchIgnore are patterns ignored unless negated on the Taskfile
+var defaultWatchIgnore = []string{"node_modules/"}
+
+func (e *Executor) setupWatchIgnore() error {
+	dir, err := filepath.Abs(e.Dir)
+	if err != nil {
+		return err
+	}
+
+	patterns := append([]string{}, defaultWatchIgnore...)
+	patterns = append(patterns, e.Taskfile.Watch.Ignore...)
+
+	root := dir
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			root = d
+			break
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	// the patterns of the Taskfile come after the .gitignore files, so
+	// they can un-ignore the paths ignored by them
+	e.watchIgnore = &ignore.Tree{Root: root, Overrides: ignore.New(dir, patterns)}
+	return nil
 }
 
 func (e *Executor) isIgnored(file string) bool {
-	return strings.Contains(file, "/.git/") || strings.Contains(file, "/node_modules/")
+	if strings.Contains(filepath.ToSlash(file), "/.git/") {
+		return true
+	}
+	if filepath.Base(file) == ".gitignore" {
+		e.stateMu.RLock()
+		e.watchIgnore.Forget(filepath.Dir(file))
+		e.stateMu.RUnlock()
+	}
+	return e.isIgnoredPath(file, false)
+}
+
+// isIgnoredPath is like isIgnored, but has no side effects so it can be
+// used while scanning. Events don't tell whether the path is a directory,
+// and the file system is not checked, so they are matched as files
+func (e *Executor) isIgnoredPath(file string, isDir bool) bool {
+	if filepath.Base(file) == ".git" {
+		return true
+	}
+	e.stateMu.RLock()
+	watchIgnore := e.watchIgnore
+	e.stateMu.RUnlock()
+	return watchIgnore.IgnoredPath(file, isDir)
 }
 
 func (e *Executor) walkTask(call taskfile.Call, visit func(*taskfile.Task) error) error {
@@ -179,96 +388,44 @@ func reduceWatchPaths(paths []string) []string {
 }
 
 
-func (e *Executor) getTaskWatchPaths(call taskfile.Call) ([]string, error) {
-	watchedPaths := make(map[string]void)
-
-	err := e.walkTask(call, func(task *taskfile.Task) error {
-
-		files, err := getWatchPathsFromGlobs(task.Dir, task.Sources)
-		for _, f := range files {
-			watchedPaths[f] = void{}
-		}
-
-		if err != nil {
-			e.Logger.Errf("task: Unable to determine watch paths for %s: sources: %v, %v", task.Task, task.Sources, err)
-			return err
-		}
-
-		return nil
-
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	var paths []string
-
-	for path := range watchedPaths {
-		paths = append(paths, path)
-	}
-
-	paths = reduceWatchPaths(paths)
+type watcher struct {
+	events chan notify.EventInfo
+	mu sync.Mutex
+	watchPaths []string
 
-	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+	// poller replaces notify when polling
+	poller     *poll.Watcher
+	pollerDone chan void
+}
 
-	return paths, nil
+// pollEvent adapts the events of the poller to the ones of notify
+type pollEvent struct {
+	event poll.Event
 }
 
-func (e *Executor) isTaskDependency(call taskfile.Call, path string) bool {
-	if e.isIgnored(path) {
-		return false
+func (ev pollEvent) Event() notify.Event {
+	switch ev.event.Op {
+	case poll.Create:
+		return notify.Create
+	case poll.Remove:
+		return notify.Remove
 	}
+	return notify.Write
+}
 
-	var dependencies = make(map[string]void)
-	var generated []string
-
-	err := e.walkTask(call, func(task *taskfile.Task) error {
-		dir, err := filepath.Abs(task.Dir)
-		if err != nil {
-			e.Logger.Errf("task: Unable to resolve directory %v", task.Dir)
-			return err
-		}
-		files, err := status.Glob(dir, task.Sources)
-		if err != nil {
-			return err
-		}
-
-		for _, f := range files {
-			dependencies[f] = void{}
-		}
-
-		files, err = status.Glob(dir, task.Generates)
-
-		if err != nil {
-			return err
-		}
-
-		generated = append(generated, files...)
-
-		return nil
-	})
-
-	if err != nil {
-		e.Logger.Errf("task: Unable to determine whether %s is a dependency for %v", path, call)
-		return false
-	} else {
-		for _, f := range generated {
-			delete(dependencies, f)
-		}
+func (ev pollEvent) Path() string {
+	return ev.event.Path
+}
 
-		_, ok := dependencies[path]
-		return ok
-	}
+func (ev pollEvent) Sys() interface{} {
+	return nil
 }
 
-type watcher struct {
-	events chan notify.EventInfo
-	mu sync.Mutex
-	watchPaths []string
+func (ev pollEvent) String() string {
+	return ev.event.Path + ": " + ev.event.Op.String()
 }
 
-func (r *watcher) rewatchPaths(e *Executor, call taskfile.Call, watchPaths []string) error {
+func (r *watcher) rewatchPaths(e *Executor, name string, watchPaths []string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -278,24 +435,42 @@ func (r *watcher) rewatchPaths(e *Executor, call taskfile.Call, watchPaths []str
 
 	r.events = make(chan notify.EventInfo, 30)
 
+	if e.WatchPoll {
+		r.poller = poll.New(e.watchInterval(), nil, e.isIgnoredPath)
+	}
+
 	for _, watchPath := range watchPaths {
-		e.Logger.VerboseOutf("task: Watching %s for %s", watchPath, call.Task)
-		err := notify.Watch(watchPath, r.events, notify.All)
+		e.Logger.VerboseOutf("task: Watching %s for %s", watchPath, name)
+		var err error
+		if r.poller != nil {
+			err = r.poller.Add(watchPath)
+		} else {
+			err = notify.Watch(watchPath, r.events, notify.All)
+		}
 		if err != nil {
-			e.Logger.Errf("task: Unable to watch %s for %s", watchPath, call.Task)
+			e.Logger.Errf("task: Unable to watch %s for %s", watchPath, name)
 		}
 	}
 
+	if r.poller != nil {
+		r.poller.Start()
+		r.pollerDone = make(chan void)
+		go func(poller *poll.Watcher, events chan notify.EventInfo, done chan void) {
+			defer close(done)
+			for event := range poller.Events {
+				select {
+				case events <- pollEvent{event}:
+				default:
+					// drops events like notify does when nobody is listening
+				}
+			}
+		}(r.poller, r.events, r.pollerDone)
+	}
+
 	return nil
 }
 
-func (r *watcher)rewatch(e *Executor, call taskfile.Call) error {
-	watchPaths, err := e.getTaskWatchPaths(call)
-
-	if err != nil {
-		return err
-	}
-
+func (r *watcher) rewatchIfChanged(e *Executor, name string, watchPaths []string) error {
 	shouldRewatch := false
 
 	if len(r.watchPaths) != len(watchPaths) {
@@ -310,13 +485,18 @@ func (r *watcher)rewatch(e *Executor, call taskfile.Call) error {
 	}
 
 	if shouldRewatch {
-		return r.rewatchPaths(e, call, watchPaths)
+		return r.rewatchPaths(e, name, watchPaths)
 	}
 
 	return nil
 }
 
 func (r *watcher) reallyClose() {
+	if r.poller != nil {
+		r.poller.Close()
+		<-r.pollerDone
+		r.poller = nil
+	}
 	events := r.events
 	if events != nil {
 		notify.Stop(events)
@@ -324,6 +504,16 @@ func (r *watcher) reallyClose() {
 	}
 }
 
+// setInterval changes the scan interval of the poller, if any
+func (r *watcher) setInterval(interval time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.poller != nil {
+		r.poller.SetInterval(interval)
+	}
+}
+
 func (r *watcher) close() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -334,79 +524,380 @@ func (r *watcher) close() {
 func newWatcher() *watcher {
 	return &watcher{}
 }
+// watchCommand is sent to the watched calls to act on them
+type watchCommand int
+
+const (
+	// watchReload is sent after the Taskfile was reloaded
+	watchReload watchCommand = iota
+	// watchRerun reruns all the tasks of the call
+	watchRerun
+	// watchForceRerun reruns all the tasks, even when up-to-date
+	watchForceRerun
+	// watchPrintPaths prints the paths watched for the call
+	watchPrintPaths
+)
+
+// sendCommand sends cmd to all the watched calls, without blocking on the
+// ones that are busy
+func sendCommand(commands []chan watchCommand, cmd watchCommand) {
+	for _, c := range commands {
+		select {
+		case c <- cmd:
+		default:
+		}
+	}
+}
+
 // start watching a call
 // runs a call, reruns when a dependent file changes
 // caller should be able to stop the routine
 // caller should be able to know that the routine has completed
-func (e *Executor) watchTask(interrupted chan void, call taskfile.Call) error {
-	_, cancel := e.runCalls(call)
+func (e *Executor) watchTask(interrupted chan void, commands chan watchCommand, call taskfile.Call) error {
+	// mu makes sure a rerun only starts after the invalidated runs stopped,
+	// and that nothing runs once watching stopped
+	var mu sync.Mutex
+	stopped := false
+	memo := newRunMemo()
+	e.startRun(memo, nil, nil, false, call)
+
+	stop := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = true
+		memo.stopAll()
+	}
 
 	w := newWatcher()
 	defer w.close()
 
 	e.Logger.Outf("task: Started watching %s", call.Task)
 
-	err := w.rewatch(e, call)
+	idx, err := e.newWatchIndex(call)
+	if err == nil {
+		err = w.rewatchIfChanged(e, call.Task, idx.paths)
+	}
 	if err != nil {
+		stop()
 		return err
 	}
 
-	debounce, cancelDebounce := newDebouncer(debounceTime)
+	errc := make(chan error, 1)
+	sendErr := func(err error) {
+		select {
+		case errc <- err:
+		default:
+		}
+	}
 
-	errc := make(chan error)
+	// rerun stops the runs of the given tasks and of the ones that depend
+	// on them, and runs the call again, forcing the forced tasks. The runs
+	// of the other tasks are kept, even if they are still running
+	rerun := func(changed []string, keys []string, forced []string, force bool) {
+		mu.Lock()
+		if stopped {
+			mu.Unlock()
+			return
+		}
+		affected := idx.affected(keys)
+		memo.invalidate(affected)
+
+		// vars only change when they are reset, so the index remains valid
+		// otherwise, or if resetting them gave the same values. Only the
+		// vars of the tasks that run again are evaluated, since the others
+		// keep their runs
+		var err error
+		if s := e.snapshot(); s.Taskfile.ResetVarsOnRerun {
+			s.Compiler.Reset()
+			if idx.varsChanged(e, affected) {
+				var newIdx *watchIndex
+				if newIdx, err = e.newWatchIndex(call); err == nil {
+					idx = newIdx
+				}
+			}
+		}
+		memo.retain(idx.keys())
+
+		e.startRun(memo, changed, forced, force, call)
+		paths := idx.paths
+		mu.Unlock()
+
+		if err == nil {
+			err = w.rewatchIfChanged(e, call.Task, paths)
+		}
+		if err != nil {
+			sendErr(err)
+		}
+	}
+
+	debounce, cancelDebounce := newDebouncer(e.watchInterval, func(batch []string) {
+		mu.Lock()
+		current := idx
+		mu.Unlock()
+
+		var changed, owners, forced []string
+		seen := make(map[string]void)
+		for _, path := range batch {
+			if _, ok := seen[path]; ok {
+				continue
+			}
+			seen[path] = void{}
+			if o, watched := current.owners(path); len(o) > 0 {
+				changed = append(changed, path)
+				owners = append(owners, o...)
+				forced = append(forced, watched...)
+			}
+		}
+		if len(changed) > 0 {
+			sort.Strings(changed)
+			e.Logger.VerboseOutf("task: Triggering rerun of %v due to changes on %s", call.Task, strings.Join(changed, ", "))
+			rerun(changed, owners, forced, false)
+		}
+	})
 
 	for {
 		select {
 		case event := <-w.events:
-			if event != nil {
-				debounce(func() {
-					if e.isTaskDependency(call, event.Path()) {
-						e.Logger.VerboseOutf("task: Triggering rerun of %v due to event %v", call.Task, event)
-
-						cancel()
-						if e.Taskfile.ResetVarsOnRerun {
-							e.Compiler.Reset()
-						}
-						_, cancel = e.runCalls(call)
-
-						err = w.rewatch(e, call)
-						if err != nil {
-							errc <- err
-						}
-					}
-				})
+			if event != nil && !e.isIgnored(event.Path()) {
+				e.Logger.VerboseOutf("task: Received event %v", event)
+				debounce(event.Path())
+			}
+		case cmd := <-commands:
+			if cmd == watchRerun || cmd == watchForceRerun {
+				mu.Lock()
+				var keys []string
+				for key := range idx.keys() {
+					keys = append(keys, key)
+				}
+				mu.Unlock()
+				go rerun(nil, keys, nil, cmd == watchForceRerun)
+				continue
+			}
+			if cmd == watchPrintPaths {
+				w.mu.Lock()
+				for _, p := range w.watchPaths {
+					e.Logger.Outf("task: Watching %s for %s", p, call.Task)
+				}
+				w.mu.Unlock()
+				continue
+			}
+
+			w.setInterval(e.watchInterval())
+
+			mu.Lock()
+			var keys []string
+			newIdx, err := e.newWatchIndex(call)
+			if err != nil {
+				// runs the call again to report the error
+				keys = []string{runKey(call)}
+			} else {
+				keys = idx.changed(newIdx)
+				idx = newIdx
+			}
+			paths := idx.paths
+			mu.Unlock()
+
+			if len(keys) > 0 {
+				e.Logger.Outf("task: Restarting %s since the Taskfile changed", call.Task)
+				go rerun(nil, keys, nil, false)
+			} else if err := w.rewatchIfChanged(e, call.Task, paths); err != nil {
+				sendErr(err)
 			}
 		case err := <- errc:
-			cancel()
 			cancelDebounce()
+			stop()
 			return err
 		case <-interrupted:
-			cancel()
 			cancelDebounce()
+			stop()
 			return nil
 		}
 	}
 }
 
-// watchTasks start watching the given tasks
-func (e *Executor) watchTasks(calls ...taskfile.Call) error {
+// taskfileFiles returns the absolute paths of the files the Taskfile was
+// read from, and of the ones that would be read if they existed
+func (e *Executor) taskfileFiles() map[string]void {
+	files := make(map[string]void)
+	add := func(path string) {
+		if abs, err := filepath.Abs(path); err == nil {
+			files[abs] = void{}
+		}
+	}
+
+	for _, f := range e.Taskfile.Files {
+		add(f)
+		add(read.OSTaskfile(f))
+	}
+	if len(e.Taskfile.Files) > 0 {
+		add(read.LocalTaskfile(e.Taskfile.Files[0]))
+	}
+	for _, f := range read.TaskvarsFiles(e.taskfileDir) {
+		add(f)
+	}
+	for _, f := range e.dotenvFiles {
+		add(f)
+	}
+	return files
+}
+
+// watchTaskfile reloads the Taskfile when any of its files change, and
+// notifies the watched tasks through commands. An invalid Taskfile is
+// reported, and the previous one is kept
+func (e *Executor) watchTaskfile(interrupted chan void, commands []chan watchCommand) {
+	var mu sync.Mutex
+	var files map[string]void
+
+	w := newWatcher()
+	defer w.close()
+
+	// directories are watched, since editors often replace files on save
+	rewatch := func() {
+		mu.Lock()
+		files = e.snapshot().taskfileFiles()
+		dirs := make(map[string]void)
+		for f := range files {
+			dirs[filepath.Dir(f)+"/"] = void{}
+		}
+		var paths []string
+		for d := range dirs {
+			if _, err := os.Stat(d); err == nil {
+				paths = append(paths, d)
+			}
+		}
+		mu.Unlock()
+
+		sort.Strings(paths)
+		if err := w.rewatchIfChanged(e, "the Taskfile", paths); err != nil {
+			e.Logger.Errf("task: Unable to watch the Taskfile: %v", err)
+		}
+	}
+	isTaskfileFile := func(path string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		_, ok := files[path]
+		return ok
+	}
+
+	rewatch()
+
+	debounce, cancelDebounce := newDebouncer(e.watchInterval, func([]string) {
+		e.Logger.Outf("task: Taskfile changed, reloading")
+		if err := e.reloadTaskfile(); err != nil {
+			e.Logger.Errf("task: Unable to reload the Taskfile: %v", err)
+			return
+		}
+		w.setInterval(e.watchInterval())
+		rewatch()
+		sendCommand(commands, watchReload)
+	})
+	defer cancelDebounce()
+
+	for {
+		select {
+		case event := <-w.events:
+			if event == nil || !isTaskfileFile(event.Path()) {
+				continue
+			}
+			debounce(event.Path())
+		case <-interrupted:
+			return
+		}
+	}
+}
+
+// reloadTaskfile reads the Taskfile on a copy of the Executor, and only
+// replaces the state of e with the one of the copy if it's valid. Runs keep
+// the snapshot they started with
+func (e *Executor) reloadTaskfile() error {
+	next := e.snapshot()
+	if err := next.loadTaskfile(); err != nil {
+		return err
+	}
+	if err := next.setupWatchIgnore(); err != nil {
+		return err
+	}
+	if err := next.setupStopSignal(); err != nil {
+		return err
+	}
+
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+	e.Dir = next.Dir
+	e.Taskfile = next.Taskfile
+	e.taskfileDir = next.taskfileDir
+	e.dotenvFiles = next.dotenvFiles
+	e.taskvars = next.taskvars
+	e.Compiler = next.Compiler
+	e.Output = next.Output
+	e.taskCallCount = next.taskCallCount
+	e.mkdirMutexMap = next.mkdirMutexMap
+	e.watchIgnore = next.watchIgnore
+	e.stopSignal = next.stopSignal
+	e.gracePeriod = next.gracePeriod
+	return nil
+}
+
+// snapshot returns a copy of the Executor, whose Taskfile and the state
+// depending on it are not replaced when the Taskfile is reloaded
+func (e *Executor) snapshot() *Executor {
+	e.stateMu.RLock()
+	defer e.stateMu.RUnlock()
+	s := *e
+	return &s
+}
+
+// watchTasks start watching the given tasks, until interrupted or ctx is
+// done
+func (e *Executor) watchTasks(ctx context.Context, calls ...taskfile.Call) error {
+	if err := e.setupWatchIgnore(); err != nil {
+		return err
+	}
+	if err := e.setupStopSignal(); err != nil {
+		return err
+	}
+
 	interrupted := make(chan void)
-	closeOnInterrupt(interrupted)
+	var once sync.Once
+	interrupt := func() {
+		once.Do(func() { close(interrupted) })
+	}
+	closeOnInterrupt(interrupt)
+	go func() {
+		select {
+		case <-ctx.Done():
+			interrupt()
+		case <-interrupted:
+		}
+	}()
 
 	wg := sync.WaitGroup{}
 
-	watchTask := func(call taskfile.Call) {
-		err := e.watchTask(interrupted, call)
+	watchTask := func(call taskfile.Call, commands chan watchCommand) {
+		err := e.watchTask(interrupted, commands, call)
 		if err != nil {
 			e.Logger.Errf("task: Unable to watch task %s: %v", call.Task, err)
 		}
 		wg.Done()
 	}
 
-	for _, call := range calls {
+	commands := make([]chan watchCommand, len(calls))
+	for i := range calls {
+		commands[i] = make(chan watchCommand, 4)
+	}
+
+	// the console is started first, so commands don't get the terminal
+	// as their stdin
+	if err := e.startConsole(interrupt, commands); err != nil {
+		e.Logger.VerboseErrf("task: Unable to start the watch console: %v", err)
+	}
+	defer e.stopConsole()
+
+	for i, call := range calls {
 		wg.Add(1)
-		go watchTask(call)
+		go watchTask(call, commands[i])
 	}
+	go e.watchTaskfile(interrupted, commands)
 
 	wg.Wait()
 	return nil
@@ -414,25 +905,43 @@ func (e *Executor) watchTasks(calls ...taskfile.Call) error {
 
 type debouncer struct {
 	mu    sync.Mutex
-	after time.Duration
+	after func() time.Duration
 	timer *time.Timer
+	f     func(batch []string)
+	batch []string
 }
 
-func newDebouncer(after time.Duration) (func(f func()), func()) {
-	d := &debouncer{after: after}
+// newDebouncer returns a func that adds a path to the current batch, and
+// calls f with all the paths of the batch once none was added for after,
+// which is called on each addition so its result can change
+func newDebouncer(after func() time.Duration, f func(batch []string)) (func(path string), func()) {
+	d := &debouncer{after: after, f: f}
 
-	return func(f func()) {
-			d.add(f)
+	return func(path string) {
+			d.add(path)
 		}, func() {
 			d.cancel()
 		}
 }
 
-func (d *debouncer) add(f func()) {
+func (d *debouncer) add(path string) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	d.reallyCancel()
-	d.timer = time.AfterFunc(d.after, f)
+	d.batch = append(d.batch, path)
+	d.timer = time.AfterFunc(d.after(), d.flush)
+}
+
+func (d *debouncer) flush() {
+	d.mu.Lock()
+	batch := d.batch
+	d.batch = nil
+	d.mu.Unlock()
+
+	// a path may have been added while the timer fired
+	if len(batch) > 0 {
+		d.f(batch)
+	}
 }
 
 func (d *debouncer) reallyCancel() {
@@ -445,19 +954,22 @@ func (d *debouncer) cancel() {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 	d.reallyCancel()
-
+	d.batch = nil
 }
 
 func isContextError(err error) bool {
+	if runErr, ok := err.(*taskRunError); ok {
+		err = runErr.err
+	}
 	return err == context.Canceled || err == context.DeadlineExceeded
 }
 
-func closeOnInterrupt(interrupted chan void) {
+func closeOnInterrupt(interrupt func()) {
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, os.Interrupt, os.Kill, syscall.SIGTERM)
 	go func() {
 		<-ch
-		close(interrupted)
+		interrupt()
 	}()
 }
 
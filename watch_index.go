@@ -0,0 +1,208 @@
+package task
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/leiyangyou/task/v2/internal/ignore"
+	"github.com/leiyangyou/task/v2/internal/status"
+	"github.com/leiyangyou/task/v2/internal/taskfile"
+)
+
+// watchIndex holds what watch mode needs to know about the compiled tasks
+// of a watched call, so events can be matched against their sources and
+// generates patterns without compiling tasks or touching the file system.
+// It only needs to be rebuilt when the vars or the Taskfile change.
+type watchIndex struct {
+	// paths are the paths to be watched, in the notation of notify
+	paths []string
+
+	// nodes are the compiled tasks of the graph by runKey
+	nodes map[string]*watchNode
+	// dependents are the keys of the tasks calling each task
+	dependents map[string][]string
+	generated  []func(string) bool
+}
+
+type watchNode struct {
+	call taskfile.Call
+	task *taskfile.Task
+	// sources and watch match the sources and the watch globs
+	sources func(string) bool
+	watch   func(string) bool
+	ignore  *ignore.Matcher
+}
+
+func (e *Executor) newWatchIndex(call taskfile.Call) (*watchIndex, error) {
+	e = e.indexExecutor()
+	idx := &watchIndex{
+		nodes:      make(map[string]*watchNode),
+		dependents: make(map[string][]string),
+	}
+	watchPaths := make(map[string]void)
+
+	if err := idx.add(e, call, "", watchPaths); err != nil {
+		return nil, err
+	}
+
+	for p := range watchPaths {
+		idx.paths = append(idx.paths, p)
+	}
+	idx.paths = reduceWatchPaths(idx.paths)
+	sort.Sort(sort.Reverse(sort.StringSlice(idx.paths)))
+
+	return idx, nil
+}
+
+// add walks the graph like walkTask, calling the tasks the same way they
+// are called when run, so the keys match the ones of runMemo
+func (idx *watchIndex) add(e *Executor, call taskfile.Call, dependent string, watchPaths map[string]void) error {
+	key := runKey(call)
+	if dependent != "" {
+		idx.dependents[key] = append(idx.dependents[key], dependent)
+	}
+	if _, ok := idx.nodes[key]; ok {
+		return nil
+	}
+
+	t, err := e.CompiledTask(call)
+	if err != nil {
+		return err
+	}
+	node := &watchNode{call: call, task: t}
+	idx.nodes[key] = node
+
+	for _, d := range t.Deps {
+		if err = idx.add(e, taskfile.Call{Task: d.Task, Vars: t.Vars.Merge(d.Vars)}, key, watchPaths); err != nil {
+			return err
+		}
+	}
+	for _, c := range t.Cmds {
+		if c.Task != "" {
+			if err = idx.add(e, taskfile.Call{Task: c.Task, Vars: t.Vars.Merge(c.Vars)}, key, watchPaths); err != nil {
+				return err
+			}
+		}
+	}
+
+	// watch globs trigger reruns like sources, but are not part of the
+	// up-to-date checks
+	globs := append(append([]string{}, t.Sources...), t.Watch...)
+	paths, err := getWatchPathsFromGlobs(t.Dir, globs)
+	if err != nil {
+		e.Logger.Errf("task: Unable to determine watch paths for %s: sources and watch: %v, %v", t.Task, globs, err)
+		return err
+	}
+	for _, p := range paths {
+		watchPaths[p] = void{}
+	}
+
+	dir, err := filepath.Abs(t.Dir)
+	if err != nil {
+		return err
+	}
+	if node.sources, err = status.Matcher(dir, t.Sources); err != nil {
+		return err
+	}
+	if node.watch, err = status.Matcher(dir, t.Watch); err != nil {
+		return err
+	}
+	generates, err := status.Matcher(dir, t.Generates)
+	if err != nil {
+		return err
+	}
+	node.ignore = ignore.New(dir, t.WatchIgnore)
+	idx.generated = append(idx.generated, generates)
+	return nil
+}
+
+// owners returns the keys of the tasks that have the given absolute path
+// as a source or watch glob, and the ones of them that matched it with a
+// watch glob, which are forced to run since their up-to-date checks don't
+// know about it. Files generated by any of the tasks are not sources. The
+// path is matched as a file, without checking the file system
+func (idx *watchIndex) owners(path string) (owners []string, watched []string) {
+	for _, generated := range idx.generated {
+		if generated(path) {
+			return nil, nil
+		}
+	}
+
+	for key, node := range idx.nodes {
+		if node.ignore.IgnoredPath(path, false) {
+			continue
+		}
+		if node.watch(path) {
+			owners = append(owners, key)
+			watched = append(watched, key)
+		} else if node.sources(path) {
+			owners = append(owners, key)
+		}
+	}
+	return owners, watched
+}
+
+// varsChanged reports whether any of the tasks of keys compiles to other
+// vars than when the index was built, e.g. after the dynamic vars were
+// reset. Only those tasks are compiled, and their patterns are not globbed
+// again
+func (idx *watchIndex) varsChanged(e *Executor, keys map[string]void) bool {
+	e = e.indexExecutor()
+	for key := range keys {
+		node, ok := idx.nodes[key]
+		if !ok {
+			continue
+		}
+		t, err := e.CompiledTask(node.call)
+		if err != nil || !reflect.DeepEqual(t.Vars, node.task.Vars) {
+			return true
+		}
+	}
+	return false
+}
+
+// indexExecutor returns a snapshot of e compiling tasks like on the first
+// run, with no changed files
+func (e *Executor) indexExecutor() *Executor {
+	e = e.snapshot()
+	e.Compiler = changedFilesCompiler{Compiler: e.Compiler}
+	return e
+}
+
+// affected returns the given keys and the keys of all the tasks that
+// depend on them, directly or not
+func (idx *watchIndex) affected(keys []string) map[string]void {
+	result := make(map[string]void)
+	for len(keys) > 0 {
+		key := keys[len(keys)-1]
+		keys = keys[:len(keys)-1]
+		if _, ok := result[key]; ok {
+			continue
+		}
+		result[key] = void{}
+		keys = append(keys, idx.dependents[key]...)
+	}
+	return result
+}
+
+// changed returns the keys of the tasks that compile differently on other,
+// or are new on it
+func (idx *watchIndex) changed(other *watchIndex) []string {
+	var keys []string
+	for key, node := range other.nodes {
+		if old, ok := idx.nodes[key]; !ok || !reflect.DeepEqual(old.task, node.task) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// keys returns the keys of all the tasks of the graph
+func (idx *watchIndex) keys() map[string]void {
+	keys := make(map[string]void, len(idx.nodes))
+	for key := range idx.nodes {
+		keys[key] = void{}
+	}
+	return keys
+}
@@ -0,0 +1,244 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/leiyangyou/task/v2/internal/taskfile"
+)
+
+// runMemo remembers the runs of the tasks of a watched call, keyed by task
+// and vars, so a rerun only runs again the tasks that were invalidated, and
+// waits for or reuses the result of the others. Each run has its own
+// context, so it can be stopped without stopping the tasks that share it.
+// Runs are only shared across reruns: a task called more than once on the
+// same run of the call runs each time, like when not watching.
+type runMemo struct {
+	mu   sync.Mutex
+	runs map[string]*memoRun
+	// generation counts the runs of the call
+	generation int
+	// waits counts, for the key of each run, the runs it's waiting for by
+	// key, so cycles are reported instead of waiting forever
+	waits map[string]map[string]int
+	// stopped is set by stopAll, after which no run starts
+	stopped bool
+}
+
+type memoRun struct {
+	cancel context.CancelFunc
+	done   chan void
+	err    error
+	// generation is the one of the run of the call that started it
+	generation int
+}
+
+type runMemoKey struct{}
+
+// runGenerationKey holds the generation of the run of the call the tasks
+// belong to
+type runGenerationKey struct{}
+
+// runCallerKey holds the key of the run the tasks are called from
+type runCallerKey struct{}
+
+func newRunMemo() *runMemo {
+	return &runMemo{
+		runs:  make(map[string]*memoRun),
+		waits: make(map[string]map[string]int),
+	}
+}
+
+func memoFromContext(ctx context.Context) *runMemo {
+	m, _ := ctx.Value(runMemoKey{}).(*runMemo)
+	return m
+}
+
+// nextGeneration returns a context for a new run of the call
+func (m *runMemo) nextGeneration(ctx context.Context) context.Context {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generation++
+	return context.WithValue(ctx, runGenerationKey{}, m.generation)
+}
+
+// runKey identifies a call. CHANGED_FILES is left out, so the tasks not
+// affected by a change are not considered new calls
+func runKey(call taskfile.Call) string {
+	names := make([]string, 0, len(call.Vars))
+	for k := range call.Vars {
+		if k != "CHANGED_FILES" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(call.Task)
+	for _, k := range names {
+		fmt.Fprintf(&b, "\x00%s=%s\x00%s", k, call.Vars[k].Static, call.Vars[k].Sh)
+	}
+	return b.String()
+}
+
+// run returns the result of the run of call, starting it if needed. The
+// run keeps the values of ctx but not its cancellation, so when ctx is done
+// only the caller stops waiting. A call already run by the same generation
+// runs again, with the cancellation of ctx, without being remembered. Calls
+// that would end up waiting on themselves fail with a cyclic dependency
+// error
+func (m *runMemo) run(ctx context.Context, call taskfile.Call, runTask func(context.Context, taskfile.Call) error) error {
+	key := runKey(call)
+	caller, _ := ctx.Value(runCallerKey{}).(string)
+	generation, _ := ctx.Value(runGenerationKey{}).(int)
+
+	m.mu.Lock()
+	if m.stopped {
+		m.mu.Unlock()
+		return context.Canceled
+	}
+	if caller != "" {
+		if key == caller || m.isWaiting(key, caller) {
+			m.mu.Unlock()
+			return &cyclicDepError{task: call.Task}
+		}
+		m.addWait(caller, key, 1)
+		defer func() {
+			m.mu.Lock()
+			m.addWait(caller, key, -1)
+			m.mu.Unlock()
+		}()
+	}
+	r, ok := m.runs[key]
+	if ok && r.generation == generation {
+		m.mu.Unlock()
+		return runTask(runContext(ctx, key), call)
+	}
+	if !ok {
+		runCtx, cancel := context.WithCancel(detachedContext{ctx})
+		r = &memoRun{cancel: cancel, done: make(chan void), generation: generation}
+		m.runs[key] = r
+		go func() {
+			r.err = runTask(runContext(runCtx, key), call)
+			close(r.done)
+		}()
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runContext returns the context to run the task of key with, which is
+// forced if its key is one of the forced keys of the run, so the tasks it
+// calls don't inherit it
+func runContext(ctx context.Context, key string) context.Context {
+	forced, _ := ctx.Value(forcedKeysKey{}).(map[string]void)
+	_, isForced := forced[key]
+	ctx = context.WithValue(ctx, runCallerKey{}, key)
+	return context.WithValue(ctx, forcedRunKey{}, isForced)
+}
+
+// isWaiting reports whether the run of key is waiting for the one of
+// other, directly or not
+func (m *runMemo) isWaiting(key, other string) bool {
+	seen := make(map[string]void)
+	keys := []string{key}
+	for len(keys) > 0 {
+		k := keys[len(keys)-1]
+		keys = keys[:len(keys)-1]
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = void{}
+		for waited := range m.waits[k] {
+			if waited == other {
+				return true
+			}
+			keys = append(keys, waited)
+		}
+	}
+	return false
+}
+
+func (m *runMemo) addWait(key, waited string, n int) {
+	if m.waits[key] == nil {
+		m.waits[key] = make(map[string]int)
+	}
+	m.waits[key][waited] += n
+	if m.waits[key][waited] == 0 {
+		delete(m.waits[key], waited)
+		if len(m.waits[key]) == 0 {
+			delete(m.waits, key)
+		}
+	}
+}
+
+// invalidate stops the runs of the given keys, waiting for them to exit,
+// and forgets them so they run again when called. Runs that failed are
+// forgotten as well
+func (m *runMemo) invalidate(keys map[string]void) {
+	m.stop(func(key string, r *memoRun) bool {
+		if _, ok := keys[key]; ok {
+			return true
+		}
+		select {
+		case <-r.done:
+			return r.err != nil
+		default:
+			return false
+		}
+	})
+}
+
+// retain stops and forgets the runs whose keys are not in keys, e.g. calls
+// that are not part of the graph anymore
+func (m *runMemo) retain(keys map[string]void) {
+	m.stop(func(key string, _ *memoRun) bool {
+		_, ok := keys[key]
+		return !ok
+	})
+}
+
+// stopAll stops all the runs and waits for them to exit. Runs started
+// afterwards fail right away
+func (m *runMemo) stopAll() {
+	m.mu.Lock()
+	m.stopped = true
+	m.mu.Unlock()
+	m.stop(func(string, *memoRun) bool { return true })
+}
+
+func (m *runMemo) stop(shouldStop func(key string, r *memoRun) bool) {
+	m.mu.Lock()
+	var stopped []*memoRun
+	for key, r := range m.runs {
+		if shouldStop(key, r) {
+			r.cancel()
+			stopped = append(stopped, r)
+			delete(m.runs, key)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, r := range stopped {
+		<-r.done
+	}
+}
+
+// detachedContext keeps the values of a context, but is never done
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }